@@ -20,7 +20,32 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// SecretDataTransform is a transformation applied to the content of a secret key.
+// +kubebuilder:validation:Enum=base64Decode;base64Encode;trimSpace;gunzip;hexDecode;normalizeLineEndings
+type SecretDataTransform string
+
+const (
+	// SecretDataTransformBase64Decode decodes standard base64 encoded content.
+	SecretDataTransformBase64Decode SecretDataTransform = "base64Decode"
+
+	// SecretDataTransformBase64Encode encodes the content using standard base64 encoding.
+	SecretDataTransformBase64Encode SecretDataTransform = "base64Encode"
+
+	// SecretDataTransformTrimSpace removes leading and trailing white space, including newlines.
+	SecretDataTransformTrimSpace SecretDataTransform = "trimSpace"
+
+	// SecretDataTransformGunzip decompresses gzip compressed content.
+	SecretDataTransformGunzip SecretDataTransform = "gunzip"
+
+	// SecretDataTransformHexDecode decodes hex encoded content.
+	SecretDataTransformHexDecode SecretDataTransform = "hexDecode"
+
+	// SecretDataTransformNormalizeLineEndings replaces CRLF and CR line endings with LF.
+	SecretDataTransformNormalizeLineEndings SecretDataTransform = "normalizeLineEndings"
+)
+
 // SecretObjectData defines the desired state of synchronized data within a Kubernetes secret object.
+// +kubebuilder:validation:XValidation:message="jsonPath and explode are mutually exclusive.",rule="!has(self.jsonPath) || !has(self.explode) || !self.explode"
 type SecretObjectData struct {
 	// sourcePath is the data source value of the secret defined in the Secret Provider Class.
 	// This matches the path of a file in the MountResponse returned from the provider.
@@ -37,10 +62,142 @@ type SecretObjectData struct {
 	// +kubebuilder:validation:Pattern=^[A-Za-z0-9.]([-A-Za-z0-9]+([-._a-zA-Z0-9]?[A-Za-z0-9])*)?(\/([0-9]+))*$
 	// +kubebuilder:validation:Required
 	TargetKey string `json:"targetKey"`
+
+	// jsonPath selects a single field of the file content, which must be a JSON or YAML document, using a
+	// JSONPath expression (https://kubernetes.io/docs/reference/kubectl/jsonpath/), e.g. {.password} or
+	// {.credentials.username}. String fields are stored as is, other fields are stored JSON encoded.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +optional
+	JSONPath string `json:"jsonPath,omitempty"`
+
+	// explode stores each top-level property of the file content, which must be a JSON or YAML object, under
+	// its own key in the Kubernetes secret's data field, named after the property. The targetKey only
+	// identifies the entry and is not used as a key.
+	// +optional
+	Explode bool `json:"explode,omitempty"`
+
+	// transforms is a list of transformations applied in order to the content of the key, after the field
+	// selected by jsonPath or explode is extracted and before the certificate and the private key are split
+	// for secrets of type kubernetes.io/tls.
+	// +kubebuilder:validation:MaxItems=8
+	// +listType=atomic
+	// +optional
+	Transforms []SecretDataTransform `json:"transforms,omitempty"`
+
+	// optional allows the file to be missing from the MountResponse returned from the provider. A missing
+	// optional key is skipped, or keeps its previous value if the missingKeyPolicy is KeepPrevious.
+	// +optional
+	Optional bool `json:"optional,omitempty"`
+}
+
+// SecretKeyRewriteRegexp rewrites secret keys using a regular expression.
+type SecretKeyRewriteRegexp struct {
+	// source is a regular expression (https://github.com/google/re2/wiki/Syntax) matched against the key.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Required
+	Source string `json:"source"`
+
+	// target replaces every match of source. $1, ${name} and similar reference the capture groups of source.
+	// +kubebuilder:validation:MaxLength=253
+	// +optional
+	Target string `json:"target"`
+}
+
+// SecretKeyRewrite defines a single rewrite of the keys of the files selected by dataFrom.
+// Exactly one of prefix, stripPrefix or regexp must be set.
+// +kubebuilder:validation:XValidation:message="Exactly one of prefix, stripPrefix or regexp must be set.",rule="[has(self.prefix), has(self.stripPrefix), has(self.regexp)].filter(x, x).size() == 1"
+type SecretKeyRewrite struct {
+	// prefix is added to the key.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// stripPrefix is removed from the key if the key starts with it.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +optional
+	StripPrefix string `json:"stripPrefix,omitempty"`
+
+	// regexp replaces the matches of a regular expression in the key.
+	// +optional
+	Regexp *SecretKeyRewriteRegexp `json:"regexp,omitempty"`
+}
+
+// InvalidKeyPolicy describes how keys that are not valid Kubernetes secret keys are handled.
+// +kubebuilder:validation:Enum=Reject;Sanitize
+type InvalidKeyPolicy string
+
+const (
+	// InvalidKeyPolicyReject fails the synchronization if a key is not a valid secret key.
+	InvalidKeyPolicyReject InvalidKeyPolicy = "Reject"
+
+	// InvalidKeyPolicySanitize replaces every character that is not allowed in a secret key with '_'.
+	InvalidKeyPolicySanitize InvalidKeyPolicy = "Sanitize"
+)
+
+// SecretObjectDataFrom selects files returned from the provider to be synchronized without listing each of them.
+type SecretObjectDataFrom struct {
+	// include is a list of glob patterns (https://pkg.go.dev/path#Match) matched against the path of the files
+	// in the MountResponse returned from the provider. All files are included if the list is empty.
+	// +kubebuilder:validation:MaxItems=32
+	// +kubebuilder:validation:items:MaxLength=253
+	// +listType=atomic
+	// +optional
+	Include []string `json:"include,omitempty"`
+
+	// exclude is a list of glob patterns of files that are not synchronized, even if they are included.
+	// +kubebuilder:validation:MaxItems=32
+	// +kubebuilder:validation:items:MaxLength=253
+	// +listType=atomic
+	// +optional
+	Exclude []string `json:"exclude,omitempty"`
+
+	// rewrite is a list of rewrites applied in order to the file path to compute the key in the
+	// Kubernetes secret's data field. The file path is used as the key if the list is empty.
+	// +kubebuilder:validation:MaxItems=16
+	// +listType=atomic
+	// +optional
+	Rewrite []SecretKeyRewrite `json:"rewrite,omitempty"`
+
+	// invalidKeyPolicy specifies how keys that are not valid Kubernetes secret keys are handled after the
+	// rewrites. Reject fails the synchronization, Sanitize replaces the characters that are not allowed
+	// with '_'. Keys that are still invalid after sanitization, or that collide with another key, are
+	// always rejected.
+	// +kubebuilder:default:=Reject
+	// +optional
+	InvalidKeyPolicy InvalidKeyPolicy `json:"invalidKeyPolicy,omitempty"`
+}
+
+// SecretObjectTemplate defines Go templates rendering data of the Kubernetes secret object.
+type SecretObjectTemplate struct {
+	// data maps keys of the Kubernetes secret's data field to Go templates (https://pkg.go.dev/text/template).
+	// The templates are executed with the files returned from the provider as data, keyed by their path,
+	// e.g. {{ .password }} or {{ index . "db/password" }}. Referencing a missing file fails the rendering.
+	// Besides the builtin functions, the following helpers are available: b64enc, b64dec, toJson, fromJson,
+	// pemCertificates and pemPrivateKey.
+	// The keys must not be set by secretObject.data or secretObject.dataFrom as well.
+	// +kubebuilder:validation:MinProperties=1
+	// +kubebuilder:validation:MaxProperties=64
+	// +kubebuilder:validation:XValidation:message="Template keys must consist of alphanumeric characters, '-', '_' or '.' and must not exceed 253 characters.",rule="self.all(k, k.size() <= 253 && k.matches('^[-._a-zA-Z0-9]+$'))"
+	// +kubebuilder:validation:Required
+	Data map[string]string `json:"data"`
 }
 
 // SecretObject defines the desired state of synchronized Kubernetes secret objects.
+// +kubebuilder:validation:XValidation:message="At least one of data, dataFrom or template must be set.",rule="has(self.data) || has(self.dataFrom) || has(self.template)"
+// +kubebuilder:validation:XValidation:message="Keys set by data must not be set by template.",rule="!has(self.data) || !has(self.template) || self.data.all(d, !(d.targetKey in self.template.data))"
 type SecretObject struct {
+	// name is the name of the Kubernetes secret object. Defaults to the name of the SecretSync.
+	// The secret must not be managed by another SecretSync. When the name is changed, the secret
+	// with the previous name is cleaned up according to the deletionPolicy once the new secret is synced.
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$
+	// +optional
+	Name string `json:"name,omitempty"`
+
 	// type specifies the type of the Kubernetes secret object,
 	// e.g. "Opaque";"kubernetes.io/basic-auth";"kubernetes.io/ssh-auth";"kubernetes.io/tls"
 	// The controller must have permission to create secrets of the specified type.
@@ -52,10 +209,22 @@ type SecretObject struct {
 	// data is a list of SecretObjectData containing secret data source from the Secret Provider Class and the
 	// corresponding data field key used in the Kubernetes secret object.
 	// +kubebuilder:validation:MinItems=1
-	// +kubebuilder:validation:Required
 	// +listType=map
 	// +listMapKey=targetKey
-	Data []SecretObjectData `json:"data"`
+	// +optional
+	Data []SecretObjectData `json:"data,omitempty"`
+
+	// dataFrom synchronizes all the files returned from the provider that match its selectors. The keys
+	// must not collide with the keys set by data, template, or another dataFrom entry.
+	// +kubebuilder:validation:MaxItems=16
+	// +listType=atomic
+	// +optional
+	DataFrom []SecretObjectDataFrom `json:"dataFrom,omitempty"`
+
+	// template renders additional keys of the Kubernetes secret object from the files returned from the
+	// provider, e.g. to compose a connection string or a .dockerconfigjson from several secrets.
+	// +optional
+	Template *SecretObjectTemplate `json:"template,omitempty"`
 
 	// labels contains key-value pairs representing labels associated with the Kubernetes secret object.
 	// The labels are used to identify the secret object created by the controller.
@@ -79,10 +248,43 @@ type SecretObject struct {
 	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
+// MissingKeyPolicy describes how keys whose file is missing from the provider response are handled.
+// +kubebuilder:validation:Enum=Fail;Skip;KeepPrevious
+type MissingKeyPolicy string
+
+const (
+	// MissingKeyPolicyFail fails the synchronization if a file that is not optional is missing.
+	MissingKeyPolicyFail MissingKeyPolicy = "Fail"
+
+	// MissingKeyPolicySkip synchronizes the secret without the missing keys.
+	MissingKeyPolicySkip MissingKeyPolicy = "Skip"
+
+	// MissingKeyPolicyKeepPrevious keeps the value of the missing keys that is already in the secret.
+	MissingKeyPolicyKeepPrevious MissingKeyPolicy = "KeepPrevious"
+)
+
+// DeletionPolicy describes what happens to the synchronized Kubernetes secret when the SecretSync is deleted.
+// +kubebuilder:validation:Enum=Delete;Retain;Orphan
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the secret along with the SecretSync.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+
+	// DeletionPolicyRetain keeps the secret and releases it from the controller by removing
+	// the owner reference and the controller label. The controller will not modify it again.
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+
+	// DeletionPolicyOrphan keeps the secret and only removes the owner reference. A SecretSync
+	// created later with the same name takes the secret over.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
 // SecretSyncSpec defines the desired state for synchronizing secret.
 type SecretSyncSpec struct {
 	// secretSyncControllerName specifies the name of the secrets store sync controller used to synchronize
-	// the secret.
+	// the secret. Only the controller instance started with a matching --controller-name processes this
+	// object. The empty default matches controllers started without a name.
 	// +optional
 	// +kubebuilder:default:=""
 	SecretSyncControllerName string `json:"secretSyncControllerName"`
@@ -105,10 +307,38 @@ type SecretSyncSpec struct {
 	// +kubebuilder:validation:Required
 	ServiceAccountName string `json:"serviceAccountName"`
 
+	// providerSecretRef references a secret in the namespace of the SecretSync whose data is passed to the
+	// provider as credentials, like the nodePublishSecretRef of the Secrets Store CSI Driver. The secret must
+	// be labeled secrets-store.csi.k8s.io/used=true to be used by the controller.
+	// +optional
+	ProviderSecretRef *ProviderSecretRef `json:"providerSecretRef,omitempty"`
+
 	// secretObject specifies the configuration for the synchronized Kubernetes secret object.
 	// +kubebuilder:validation:Required
 	SecretObject SecretObject `json:"secretObject"`
 
+	// deletionPolicy specifies what happens to the Kubernetes secret when the SecretSync is deleted.
+	// Delete removes the secret, Retain keeps the secret but removes its owner reference and the
+	// controller label, Orphan keeps the secret and only removes its owner reference.
+	// +kubebuilder:default:=Delete
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// missingKeyPolicy specifies how the keys of secretObject.data are handled when their file is missing
+	// from the provider response. Fail fails the synchronization unless the key is optional, Skip
+	// synchronizes the secret without the missing keys, KeepPrevious keeps the value of the missing keys
+	// that is already in the secret. An exploded entry whose file is missing keeps the keys last applied
+	// by the controller that no other entry sets anymore, and is listed by its sourcePath.
+	// The missing keys are listed in the KeysMissing condition.
+	// +kubebuilder:default:=Fail
+	// +optional
+	MissingKeyPolicy MissingKeyPolicy `json:"missingKeyPolicy,omitempty"`
+
+	// rotation specifies when the secret is synchronized again from the provider. SecretSyncs without
+	// rotation are synchronized again every --rotation-poll-interval of the controller.
+	// +optional
+	Rotation *Rotation `json:"rotation,omitempty"`
+
 	// forceSynchronization can be used to force the secret synchronization. The secret synchronization is
 	// triggered by changing the value in this field.
 	// This field is not used to resolve synchronization conflicts.
@@ -120,6 +350,40 @@ type SecretSyncSpec struct {
 	ForceSynchronization string `json:"forceSynchronization,omitempty"`
 }
 
+// ProviderSecretRef references a secret holding the credentials of the provider.
+type ProviderSecretRef struct {
+	// name of the secret in the namespace of the SecretSync.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+}
+
+// Rotation configures the periodic synchronization of the secret, either every interval or on a cron
+// schedule.
+// +kubebuilder:validation:XValidation:message="Exactly one of interval or schedule must be set.",rule="has(self.interval) != has(self.schedule)"
+type Rotation struct {
+	// interval between two synchronizations of the secret, e.g. 5m. It must be at least one minute. The
+	// SecretSyncs are synchronized at an offset in the interval derived from their UID.
+	// +kubebuilder:validation:XValidation:message="The interval must be at least one minute.",rule="duration(self) >= duration('1m')"
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// schedule is a cron expression of the synchronization times, in UTC, with the minute, hour, day of
+	// month, month and day of week fields, e.g. "0 2 * * *" for every night at 2:00. The @hourly, @daily,
+	// @midnight, @weekly, @monthly, @yearly and @annually shortcuts are supported.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// jitter is the maximum delay added to every synchronization time, to spread the load on the provider
+	// of the SecretSyncs rotated at the same time. The delay of each SecretSync is derived from its UID.
+	// +optional
+	Jitter *metav1.Duration `json:"jitter,omitempty"`
+}
+
 // SecretSyncStatus defines the observed state of the secret synchronization process.
 type SecretSyncStatus struct {
 	// syncHash contains the hash of the secret object data, data from the SecretProviderClass (e.g. UID,
@@ -135,10 +399,46 @@ type SecretSyncStatus struct {
 	// +optional
 	SyncHash string `json:"syncHash,omitempty"`
 
+	// secretName is the name of the Kubernetes secret last synchronized by the controller.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
 	// lastSuccessfulSyncTime represents the last time the secret was retrieved from the Provider and updated.
 	// +optional
 	LastSuccessfulSyncTime *metav1.Time `json:"lastSuccessfulSyncTime,omitempty"`
 
+	// lastAttemptTime is the time of the last synchronization attempt, successful or not.
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+
+	// failureCount is the number of consecutive failed synchronization attempts, reset by a successful
+	// synchronization. The retries of transient failures back off exponentially with it.
+	// +optional
+	FailureCount int32 `json:"failureCount,omitempty"`
+
+	// nextRetryTime is the time the last failed synchronization is retried. A permanent failure, e.g. an
+	// invalid spec, is retried at the next rotation. It is not set when the SecretSync is not rotated: the
+	// synchronization is attempted again once the SecretSync or the SecretProviderClass changes.
+	// +optional
+	NextRetryTime *metav1.Time `json:"nextRetryTime,omitempty"`
+
+	// nextSyncTime is the time the secret is scheduled to be synchronized again from the provider.
+	// +optional
+	NextSyncTime *metav1.Time `json:"nextSyncTime,omitempty"`
+
+	// objectVersions contains the versions of the objects, by object ID, returned by the provider
+	// in the last successful sync. They are sent back to the provider on the next sync. When the
+	// provider returns the same versions again, the returned data is neither hashed nor applied
+	// to the secret, unless the secret drifted.
+	// +optional
+	ObjectVersions map[string]string `json:"objectVersions,omitempty"`
+
+	// inputsHash is the hash of the versions of the SecretProviderClass, the SecretSync and the
+	// provider secret of the last successful sync, it does not depend on the secret data. Until the
+	// nextSyncTime, the secret is not synced again from the provider while they are unchanged.
+	// +optional
+	InputsHash string `json:"inputsHash,omitempty"`
+
 	// conditions represent the status of the secret create and update processes.
 	// The status is set to True if the secret was created or updated successfully.
 	// The status is set to False if the secret create or update failed.
@@ -219,7 +519,8 @@ type SecretSyncStatus struct {
 // +kubebuilder:storageversion
 // +kubebuilder:subresource:status
 // SecretSync represents the desired and observed state of the secret synchronization process.
-// The SecretSync name is used as the name of the secret object created by the controller.
+// The SecretSync name is used as the name of the secret object created by the controller, unless
+// spec.secretObject.name is set.
 type SecretSync struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -25,13 +25,102 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderSecretRef) DeepCopyInto(out *ProviderSecretRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderSecretRef.
+func (in *ProviderSecretRef) DeepCopy() *ProviderSecretRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderSecretRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Rotation) DeepCopyInto(out *Rotation) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Jitter != nil {
+		in, out := &in.Jitter, &out.Jitter
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rotation.
+func (in *Rotation) DeepCopy() *Rotation {
+	if in == nil {
+		return nil
+	}
+	out := new(Rotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeyRewrite) DeepCopyInto(out *SecretKeyRewrite) {
+	*out = *in
+	if in.Regexp != nil {
+		in, out := &in.Regexp, &out.Regexp
+		*out = new(SecretKeyRewriteRegexp)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyRewrite.
+func (in *SecretKeyRewrite) DeepCopy() *SecretKeyRewrite {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeyRewrite)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeyRewriteRegexp) DeepCopyInto(out *SecretKeyRewriteRegexp) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyRewriteRegexp.
+func (in *SecretKeyRewriteRegexp) DeepCopy() *SecretKeyRewriteRegexp {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeyRewriteRegexp)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretObject) DeepCopyInto(out *SecretObject) {
 	*out = *in
 	if in.Data != nil {
 		in, out := &in.Data, &out.Data
 		*out = make([]SecretObjectData, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DataFrom != nil {
+		in, out := &in.DataFrom, &out.DataFrom
+		*out = make([]SecretObjectDataFrom, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = new(SecretObjectTemplate)
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Labels != nil {
 		in, out := &in.Labels, &out.Labels
@@ -62,6 +151,11 @@ func (in *SecretObject) DeepCopy() *SecretObject {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretObjectData) DeepCopyInto(out *SecretObjectData) {
 	*out = *in
+	if in.Transforms != nil {
+		in, out := &in.Transforms, &out.Transforms
+		*out = make([]SecretDataTransform, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretObjectData.
@@ -74,6 +168,60 @@ func (in *SecretObjectData) DeepCopy() *SecretObjectData {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretObjectDataFrom) DeepCopyInto(out *SecretObjectDataFrom) {
+	*out = *in
+	if in.Include != nil {
+		in, out := &in.Include, &out.Include
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rewrite != nil {
+		in, out := &in.Rewrite, &out.Rewrite
+		*out = make([]SecretKeyRewrite, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretObjectDataFrom.
+func (in *SecretObjectDataFrom) DeepCopy() *SecretObjectDataFrom {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretObjectDataFrom)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretObjectTemplate) DeepCopyInto(out *SecretObjectTemplate) {
+	*out = *in
+	if in.Data != nil {
+		in, out := &in.Data, &out.Data
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretObjectTemplate.
+func (in *SecretObjectTemplate) DeepCopy() *SecretObjectTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretObjectTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretSync) DeepCopyInto(out *SecretSync) {
 	*out = *in
@@ -136,7 +284,17 @@ func (in *SecretSyncList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretSyncSpec) DeepCopyInto(out *SecretSyncSpec) {
 	*out = *in
+	if in.ProviderSecretRef != nil {
+		in, out := &in.ProviderSecretRef, &out.ProviderSecretRef
+		*out = new(ProviderSecretRef)
+		**out = **in
+	}
 	in.SecretObject.DeepCopyInto(&out.SecretObject)
+	if in.Rotation != nil {
+		in, out := &in.Rotation, &out.Rotation
+		*out = new(Rotation)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretSyncSpec.
@@ -156,6 +314,25 @@ func (in *SecretSyncStatus) DeepCopyInto(out *SecretSyncStatus) {
 		in, out := &in.LastSuccessfulSyncTime, &out.LastSuccessfulSyncTime
 		*out = (*in).DeepCopy()
 	}
+	if in.LastAttemptTime != nil {
+		in, out := &in.LastAttemptTime, &out.LastAttemptTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextRetryTime != nil {
+		in, out := &in.NextRetryTime, &out.NextRetryTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextSyncTime != nil {
+		in, out := &in.NextSyncTime, &out.NextSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ObjectVersions != nil {
+		in, out := &in.ObjectVersions, &out.ObjectVersions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
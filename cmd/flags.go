@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+// stringSliceFlag is a repeatable flag whose values may also be comma
+// separated. The defaults are replaced by the first value set.
+type stringSliceFlag struct {
+	values []string
+	set    bool
+}
+
+var _ flag.Value = &stringSliceFlag{}
+
+func newStringSliceFlag(name string, defaults []string, usage string) *stringSliceFlag {
+	f := &stringSliceFlag{values: defaults}
+	flag.Var(f, name, usage)
+	return f
+}
+
+func (f *stringSliceFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(f.values, ",")
+}
+
+func (f *stringSliceFlag) Set(value string) error {
+	if !f.set {
+		f.values = nil
+		f.set = true
+	}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			f.values = append(f.values, v)
+		}
+	}
+	return nil
+}
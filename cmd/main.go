@@ -17,19 +17,26 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"os"
 	"strings"
 	"time"
 
 	"google.golang.org/grpc"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -37,8 +44,10 @@ import (
 
 	secretsyncv1alpha1 "sigs.k8s.io/secrets-store-sync-controller/api/v1alpha1"
 	"sigs.k8s.io/secrets-store-sync-controller/internal/controller"
+	"sigs.k8s.io/secrets-store-sync-controller/pkg/metrics"
 	"sigs.k8s.io/secrets-store-sync-controller/pkg/provider"
 	"sigs.k8s.io/secrets-store-sync-controller/pkg/token"
+	"sigs.k8s.io/secrets-store-sync-controller/pkg/tracing"
 	"sigs.k8s.io/secrets-store-sync-controller/pkg/version"
 	//+kubebuilder:scaffold:imports
 )
@@ -51,12 +60,29 @@ var (
 	leaderElectionNamespace = flag.String("leader-election-namespace", "", "Namespace for leader election")
 	probeAddr               = flag.String("health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	tokenRequestAudiences   = flag.String("token-request-audience", "", "Audience for the token request, comma separated.")
-	providerVolumePath      = flag.String("provider-volume", "/provider", "Volume path for provider.")
-	rotationPollInterval    = flag.Duration("rotation-poll-interval", 12*time.Hour, "Polling interval to resync secrets from the provider. Defaults to 12h. To disable provider polling, set it to 0s.")
+	providerVolumePaths     = newStringSliceFlag("provider-volume", []string{"/provider"}, "Volume paths for providers, repeatable or comma separated.")
+	providerAliases         = newStringSliceFlag("provider-alias", nil, "Aliases of the providers as alias=provider pairs, repeatable or comma separated, e.g. vault=hashicorp-vault resolves the SecretProviderClass provider vault to the hashicorp-vault.sock socket.")
+	remoteProvidersConfig   = flag.String("remote-providers-config", "", "Path of the configuration file of the providers reached over TCP with mutual TLS.")
+	providerMountTimeouts   = newStringSliceFlag("provider-mount-timeout", []string{"1m"}, "Timeout of the Mount calls to the providers, including the wait for an in-flight slot, as a duration or provider=duration pairs overriding it by provider, repeatable or comma separated. 0 disables the timeout.")
+	providerMaxInFlight     = newStringSliceFlag("provider-max-in-flight-mounts", []string{"0"}, "Maximum number of concurrent Mount calls to each provider, as a number or provider=number pairs overriding it by provider, repeatable or comma separated. 0 disables the limit.")
+	breakerFailures         = flag.Int("provider-circuit-breaker-failures", 5, "Number of consecutive failed Mount calls to a provider which opens its circuit breaker. 0 disables the circuit breaker.")
+	breakerOpenDuration     = flag.Duration("provider-circuit-breaker-open-duration", 30*time.Second, "Duration an open circuit breaker fails the Mount calls to the provider before trying a single call again.")
+	mountCacheTTL           = flag.Duration("mount-cache-ttl", 10*time.Second, "Duration the Mount responses are shared between the SecretSyncs with the same SecretProviderClass and service account. To disable the cache, set it to 0s.")
+	mountCacheMaxBytes      = flag.Int("mount-cache-max-bytes", 16<<20, "Maximum size in bytes of the files in the Mount response cache.")
+	providerQPS             = flag.Float64("provider-qps", 10, "Maximum number of syncs calling the providers per second, across all providers. Mount responses shared from the cache are not limited. 0 disables the limit.")
+	providerBurst           = flag.Int("provider-burst", 10, "Maximum burst of syncs calling the providers above --provider-qps.")
+	retryMinBackoff         = flag.Duration("retry-min-backoff", controller.DefaultMinRetryBackoff, "Delay before retrying a sync which failed with a transient error, doubled on every consecutive failure.")
+	retryMaxBackoff         = flag.Duration("retry-max-backoff", controller.DefaultMaxRetryBackoff, "Maximum delay before retrying a sync which failed with a transient error.")
+	rotationPollInterval    = flag.Duration("rotation-poll-interval", 12*time.Hour, "Interval to resync the secrets of the SecretSyncs without spec.rotation from the provider. Defaults to 12h. To disable their rotation, set it to 0s.")
+	providerHealthInterval  = flag.Duration("provider-health-check-interval", time.Minute, "Interval of the provider healthchecks. To disable the healthchecks, set it to 0s.")
+	requiredProviders       = flag.String("required-providers", "", "Providers which must pass their healthcheck for the controller to be ready, comma separated.")
 	maxCallRecvMsgSize      = flag.Int("max-call-recv-msg-size", 1024*1024*4, "maximum size in bytes of gRPC response from plugins")
 	versionInfo             = flag.Bool("version", false, "Print the version and exit")
+	controllerName          = flag.String("controller-name", "", "Name of this controller instance. Only SecretSyncs with a matching spec.secretSyncControllerName are synchronized. Also used to derive a distinct leader election ID.")
 )
 
+const defaultLeaderElectionID = "29f1d54e.secret-sync.x-k8s.io"
+
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
@@ -84,16 +110,69 @@ func runMain() error {
 		return nil
 	}
 
+	leaderElectionID := defaultLeaderElectionID
+	if len(*controllerName) > 0 {
+		if errs := validation.IsDNS1123Label(*controllerName); len(errs) > 0 {
+			err := fmt.Errorf("invalid --controller-name %q: %s", *controllerName, strings.Join(errs, ", "))
+			setupLog.Error(err, "invalid flags")
+			return err
+		}
+		// each named controller instance needs its own lease so that several
+		// instances can run side by side
+		leaderElectionID = *controllerName + "." + defaultLeaderElectionID
+	}
+
 	controllerConfig := ctrl.GetConfigOrDie()
 	controllerConfig.UserAgent = version.GetUserAgent("secrets-store-sync-controller")
+	shutdownMetricsExporter, err := metrics.InitMetricsExporter()
+	if err != nil {
+		setupLog.Error(err, "failed to initialize metrics exporter")
+		return err
+	}
+	defer func() {
+		// push the pending metrics of the otlp backends before exiting
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownMetricsExporter(ctx); err != nil {
+			setupLog.Error(err, "failed to shut down metrics exporter")
+		}
+	}()
+
+	shutdownTracing, err := tracing.InitTracing()
+	if err != nil {
+		setupLog.Error(err, "failed to initialize tracing")
+		return err
+	}
+	defer func() {
+		// export the pending spans before exiting
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			setupLog.Error(err, "failed to shut down tracing")
+		}
+	}()
+
+	// only the Secrets managed by the controller are watched, and only their
+	// metadata is cached
+	managedSecretsSelector, err := labels.Parse(controller.ManagedSecretLabelSelector)
+	if err != nil {
+		setupLog.Error(err, "failed to parse managed secrets label selector")
+		return err
+	}
+
 	mgr, err := ctrl.NewManager(controllerConfig, ctrl.Options{
 		Scheme: scheme,
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.Secret{}: {Label: managedSecretsSelector},
+			},
+		},
 		Metrics: server.Options{
 			BindAddress: *metricsAddr,
 		},
 		HealthProbeBindAddress:  *probeAddr,
 		LeaderElection:          *enableLeaderElection,
-		LeaderElectionID:        "29f1d54e.secret-sync.x-k8s.io",
+		LeaderElectionID:        leaderElectionID,
 		LeaderElectionNamespace: *leaderElectionNamespace,
 	})
 	if err != nil {
@@ -105,19 +184,62 @@ func runMain() error {
 	kubeClient := kubernetes.NewForConfigOrDie(ctrl.GetConfigOrDie())
 	tokenCache := token.NewManager(kubeClient)
 
+	// the default event correlator aggregates similar events and rate limits
+	// the events emitted for each object
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartStructuredLogging(4)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+	defer eventBroadcaster.Shutdown()
+
+	aliases, err := provider.ParseAliases(providerAliases.values)
+	if err != nil {
+		setupLog.Error(err, "invalid flags")
+		return err
+	}
+	limits := provider.MountLimits{
+		FailureThreshold: *breakerFailures,
+		OpenDuration:     *breakerOpenDuration,
+	}
+	if limits.Timeout, limits.Timeouts, err = provider.ParseOverrides(providerMountTimeouts.values, time.ParseDuration, 0); err != nil {
+		setupLog.Error(err, "invalid flags")
+		return err
+	}
+	if limits.MaxInFlight, limits.MaxInFlightByProvider, err = provider.ParseOverrides(providerMaxInFlight.values, provider.ParseMaxInFlight, 0); err != nil {
+		setupLog.Error(err, "invalid flags")
+		return err
+	}
 	providerClients := provider.NewPluginClientBuilder(
-		[]string{*providerVolumePath},
+		providerVolumePaths.values,
 		grpc.WithDefaultCallOptions(
 			grpc.MaxCallRecvMsgSize(*maxCallRecvMsgSize),
 		),
 	)
 	defer providerClients.Cleanup()
+	providerClients.SetAliases(aliases)
+	providerClients.SetMountLimits(limits)
+	if len(*remoteProvidersConfig) > 0 {
+		remotes, err := provider.LoadRemoteProviders(*remoteProvidersConfig)
+		if err != nil {
+			setupLog.Error(err, "invalid remote providers config")
+			return err
+		}
+		providerClients.SetRemoteProviders(remotes)
+	}
+	if err := providerClients.ValidateSockets(); err != nil {
+		setupLog.Error(err, "invalid provider sockets")
+		return err
+	}
 
 	audiences := strings.Split(*tokenRequestAudiences, ",")
 	if len(*tokenRequestAudiences) == 0 {
 		audiences = []string{}
 	}
 
+	var mountCache *controller.MountCache
+	if *mountCacheTTL > 0 {
+		mountCache = controller.NewMountCache(*mountCacheTTL, *mountCacheMaxBytes)
+	}
+
 	if err = (&controller.SecretSyncReconciler{
 		Clientset:       kubeClient,
 		Client:          mgr.GetClient(),
@@ -125,7 +247,11 @@ func runMain() error {
 		TokenCache:      tokenCache,
 		ProviderClients: providerClients,
 		Audiences:       audiences,
-		EventRecorder:   record.NewBroadcaster().NewRecorder(scheme, corev1.EventSource{Component: "secret-sync-controller"}),
+		EventRecorder:   eventBroadcaster.NewRecorder(scheme, corev1.EventSource{Component: "secret-sync-controller"}),
+		ControllerName:  *controllerName,
+		MountCache:      mountCache,
+		Scheduler:       controller.NewRotationScheduler(*providerQPS, *providerBurst),
+		RetryBackoff:    controller.RetryBackoff{Min: *retryMinBackoff, Max: *retryMaxBackoff},
 	}).SetupWithManager(mgr, *rotationPollInterval); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "SecretSync")
 		return err
@@ -141,8 +267,32 @@ func runMain() error {
 		return err
 	}
 
+	var required []string
+	if len(*requiredProviders) > 0 {
+		required = strings.Split(*requiredProviders, ",")
+	}
+	if len(required) > 0 && *providerHealthInterval <= 0 {
+		err := fmt.Errorf("--required-providers needs the provider healthchecks, set --provider-health-check-interval")
+		setupLog.Error(err, "invalid flags")
+		return err
+	}
+	if err := mgr.AddReadyzCheck("providers", providerClients.Checker(required)); err != nil {
+		setupLog.Error(err, "unable to set up providers ready check")
+		return err
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+	if *providerHealthInterval > 0 {
+		go providerClients.HealthCheck(ctx, *providerHealthInterval, required)
+	}
+	go func() {
+		if err := providerClients.Watch(ctx); err != nil {
+			setupLog.Error(err, "failed to watch provider sockets, providers are only discovered on first use")
+		}
+	}()
+
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		return err
 	}
@@ -19,16 +19,19 @@ package controller
 import (
 	"context"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	secretsyncv1alpha1 "sigs.k8s.io/secrets-store-sync-controller/api/v1alpha1"
 )
 
 const (
-	ConditionTypeCreate = "SecretCreated"
-	ConditionTypeUpdate = "SecretUpdated"
+	ConditionTypeCreate      = "SecretCreated"
+	ConditionTypeUpdate      = "SecretUpdated"
+	ConditionTypeKeysMissing = "KeysMissing"
 
 	ConditionReasonFailedProviderError          = "ProviderError"
 	ConditionReasonFailedInvalidLabelError      = "InvalidClusterSecretLabelError"
@@ -36,7 +39,13 @@ const (
 	ConditionReasonControllerSyncError          = "ControllerSyncError"
 	ConditionReasonControllerPatchError         = "ControllerPatchError"
 	ConditionReasonControllerSpcError           = "SecretProviderClassMisconfigured"
+	ConditionReasonProviderNotFound             = "ProviderNotFound"
+	ConditionReasonProviderUnavailable          = "ProviderUnavailable"
+	ConditionReasonProviderSecretError          = "ProviderSecretError"
 	ConditionReasonRemoteSecretStoreFetchFailed = "RemoteSecretStoreFetchFailed"
+	ConditionReasonSecretNameConflict           = "SecretNameConflict"
+	ConditionReasonSecretTemplateError          = "SecretTemplateError"
+	ConditionReasonInvalidRotation              = "InvalidRotation"
 
 	ConditionReasonSyncStarting         = "SyncStarting"
 	ConditionReasonNoUpdateAttemptedYet = "NoUpdatesAttemptedYet"
@@ -44,21 +53,32 @@ const (
 	ConditionReasonSecretUpToDate   = "SecretUpToDate"
 	ConditionReasonCreateSuccessful = "CreateSuccessful"
 
+	ConditionReasonKeysSkipped        = "KeysSkipped"
+	ConditionReasonPreviousValuesKept = "PreviousValuesKept"
+	ConditionReasonAllKeysFound       = "AllKeysFound"
+
 	ConditionMessageCreateSuccessful = "Secret created successfully."
 	ConditionMessageUpdateSuccessful = "Secret contains last observed values."
+	ConditionMessageAllKeysFound     = "The files of all keys were found in the provider response."
 )
 
-var FailedConditionsTriggeringRetry = []string{ // FIXME: should be a set
+// FailedConditionsTriggeringRetry are the reasons of the failed conditions
+// which make the next sync patch the secret even if its hash did not change.
+var FailedConditionsTriggeringRetry = sets.New(
 	ConditionReasonControllerSpcError,
 	ConditionReasonFailedInvalidAnnotationError,
 	ConditionReasonFailedInvalidLabelError,
 	ConditionReasonFailedProviderError,
-	ConditionReasonFailedInvalidAnnotationError,
-	ConditionReasonFailedProviderError,
 	ConditionReasonRemoteSecretStoreFetchFailed,
 	ConditionReasonControllerPatchError,
 	ConditionReasonControllerSyncError,
-}
+	ConditionReasonSecretNameConflict,
+	ConditionReasonSecretTemplateError,
+	ConditionReasonProviderNotFound,
+	ConditionReasonProviderUnavailable,
+	ConditionReasonProviderSecretError,
+	ConditionReasonInvalidRotation,
+)
 
 var SuccessfulConditionsTriggeringRetry = []string{
 	ConditionReasonCreateSuccessful,
@@ -85,6 +105,12 @@ func (r *SecretSyncReconciler) updateStatusConditions(ctx context.Context, ss *s
 	logger.V(10).Info("Adding new condition", "newConditionType", conditionType, "conditionReason", conditionReason)
 	meta.SetStatusCondition(&ss.Status.Conditions, condition)
 
+	// every failed sync is reported as an event as well, the condition messages
+	// never contain secret values
+	if conditionStatus == metav1.ConditionFalse {
+		r.EventRecorder.Event(ss, corev1.EventTypeWarning, conditionReason, conditionMessage)
+	}
+
 	if !shouldUpdateStatus {
 		return
 	}
@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsyncv1alpha1 "sigs.k8s.io/secrets-store-sync-controller/api/v1alpha1"
+)
+
+// secretSyncFinalizer is added to every SecretSync so that the deletion policy
+// can be applied to the secret before the SecretSync is gone.
+const secretSyncFinalizer = "secret-sync.x-k8s.io/secret-cleanup"
+
+// ensureFinalizer adds the controller finalizer to the SecretSync if it is missing.
+func (r *SecretSyncReconciler) ensureFinalizer(ctx context.Context, ss *secretsyncv1alpha1.SecretSync) error {
+	if !controllerutil.AddFinalizer(ss, secretSyncFinalizer) {
+		return nil
+	}
+	return r.Update(ctx, ss)
+}
+
+// blocksOwnerDeletion returns true if the owner reference of the secret of the
+// SecretSync blocks the foreground deletion of the SecretSync, which is only
+// the case with the Delete policy.
+func blocksOwnerDeletion(ss *secretsyncv1alpha1.SecretSync) bool {
+	switch ss.Spec.DeletionPolicy {
+	case secretsyncv1alpha1.DeletionPolicyRetain, secretsyncv1alpha1.DeletionPolicyOrphan:
+		return false
+	}
+	return true
+}
+
+// finalizeSecretSync applies the deletion policy of a SecretSync that is being
+// deleted and removes the controller finalizer.
+//
+// With the Delete policy the secret is left to the garbage collector: it is
+// owned by the SecretSync with blockOwnerDeletion set, so it is removed in both
+// background and foreground deletion.
+//
+// With the Retain and Orphan policies the secret does not block the deletion of
+// the SecretSync, and it is released as soon as the deletion starts, before
+// the controller finalizer is removed. In a foreground deletion the garbage
+// collector only removes the foregroundDeletion finalizer of the SecretSync
+// once its owned secret is released.
+func (r *SecretSyncReconciler) finalizeSecretSync(ctx context.Context, ss *secretsyncv1alpha1.SecretSync) error {
+	logger := log.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(ss, secretSyncFinalizer) {
+		return nil
+	}
+
+	switch ss.Spec.DeletionPolicy {
+	case secretsyncv1alpha1.DeletionPolicyRetain:
+		if err := r.releaseSecret(ctx, ss, syncedSecretName(ss), true); err != nil {
+			return err
+		}
+	case secretsyncv1alpha1.DeletionPolicyOrphan:
+		if err := r.releaseSecret(ctx, ss, syncedSecretName(ss), false); err != nil {
+			return err
+		}
+	}
+
+	logger.V(4).Info("applied deletion policy", "deletionPolicy", ss.Spec.DeletionPolicy, "secretName", syncedSecretName(ss))
+
+	controllerutil.RemoveFinalizer(ss, secretSyncFinalizer)
+	return r.Update(ctx, ss)
+}
+
+// releaseSecret removes the owner reference pointing to the SecretSync from
+// the named secret so that it survives the garbage collection of its owner. If
+// removeLabel is set, the controller label is removed as well.
+func (r *SecretSyncReconciler) releaseSecret(ctx context.Context, ss *secretsyncv1alpha1.SecretSync, name string, removeLabel bool) error {
+	metadata := map[string]interface{}{
+		"ownerReferences": []map[string]interface{}{
+			{"$patch": "delete", "uid": ss.UID},
+		},
+	}
+	if removeLabel {
+		metadata["labels"] = map[string]interface{}{controllerLabelKey: nil}
+	}
+
+	patchData, err := json.Marshal(map[string]interface{}{"metadata": metadata})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Clientset.CoreV1().Secrets(ss.Namespace).Patch(ctx, name, types.StrategicMergePatchType, patchData, metav1.PatchOptions{FieldManager: secretSyncControllerFieldManager})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to release secret %q: %w", name, err)
+	}
+	return nil
+}
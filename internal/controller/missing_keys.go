@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	secretsyncv1alpha1 "sigs.k8s.io/secrets-store-sync-controller/api/v1alpha1"
+)
+
+// missingKeys lists the keys whose file was missing from the provider response,
+// by source path for the exploded entries.
+type missingKeys struct {
+	// skipped keys are not part of the synchronized secret.
+	skipped []string
+	// kept keys were synchronized with the value already in the secret.
+	kept []string
+}
+
+// keepPreviousValues copies the values of the missing keys from the secret
+// into datamap if the missing key policy of the SecretSync is KeepPrevious.
+// The secret is read directly from the API server as the controller does not
+// cache secret data, and only if it is managed by the controller.
+//
+// The keys of an exploded entry are not known without its file, the missing
+// exploded entries keep the keys last applied by the controller which no other
+// field of the secret object sets anymore.
+func (r *SecretSyncReconciler) keepPreviousValues(ctx context.Context, ss *secretsyncv1alpha1.SecretSync, datamap map[string][]byte, keys []string) (missingKeys, error) {
+	missing := missingKeys{}
+	if len(keys) == 0 {
+		return missing, nil
+	}
+	if ss.Spec.MissingKeyPolicy != secretsyncv1alpha1.MissingKeyPolicyKeepPrevious {
+		missing.skipped = keys
+		return missing, nil
+	}
+
+	secret, err := r.getSecret(ctx, ss.Namespace, desiredSecretName(ss))
+	if err != nil && !apierrors.IsNotFound(err) {
+		return missing, fmt.Errorf("failed to get secret %q to keep previous values: %w", desiredSecretName(ss), err)
+	}
+	managed := err == nil && hasControllerLabel(secret)
+
+	exploded := sets.New[string]()
+	for _, data := range ss.Spec.SecretObject.Data {
+		if data.Explode {
+			exploded.Insert(strings.TrimSpace(data.SourcePath))
+		}
+	}
+
+	var explodedKeys []string
+	for _, key := range keys {
+		switch {
+		case !managed:
+		case exploded.Has(key):
+			explodedKeys = append(explodedKeys, key)
+			continue
+		default:
+			if value, ok := secret.Data[key]; ok {
+				datamap[key] = value
+				missing.kept = append(missing.kept, key)
+				continue
+			}
+		}
+		missing.skipped = append(missing.skipped, key)
+	}
+	if len(explodedKeys) == 0 {
+		return missing, nil
+	}
+
+	var previous []string
+	for _, key := range controllerDataKeys(secret) {
+		if value, ok := secret.Data[key]; ok && !slices.Contains(keys, key) {
+			if _, set := datamap[key]; !set {
+				datamap[key] = value
+				previous = append(previous, key)
+			}
+		}
+	}
+	if len(previous) == 0 {
+		missing.skipped = append(missing.skipped, explodedKeys...)
+	} else {
+		missing.kept = append(missing.kept, explodedKeys...)
+	}
+	return missing, nil
+}
+
+// setMissingKeysCondition reports the missing keys in the KeysMissing condition.
+// The condition is only added once keys are missing. It returns true if the
+// conditions changed.
+func setMissingKeysCondition(ss *secretsyncv1alpha1.SecretSync, missing missingKeys) bool {
+	if len(missing.skipped) == 0 && len(missing.kept) == 0 {
+		if meta.FindStatusCondition(ss.Status.Conditions, ConditionTypeKeysMissing) == nil {
+			return false
+		}
+		return meta.SetStatusCondition(&ss.Status.Conditions, metav1.Condition{
+			Type:    ConditionTypeKeysMissing,
+			Status:  metav1.ConditionFalse,
+			Reason:  ConditionReasonAllKeysFound,
+			Message: ConditionMessageAllKeysFound,
+		})
+	}
+
+	reason := ConditionReasonPreviousValuesKept
+	var details []string
+	if len(missing.skipped) > 0 {
+		reason = ConditionReasonKeysSkipped
+		details = append(details, "skipped: "+strings.Join(missing.skipped, ", "))
+	}
+	if len(missing.kept) > 0 {
+		details = append(details, "kept previous value: "+strings.Join(missing.kept, ", "))
+	}
+
+	return meta.SetStatusCondition(&ss.Status.Conditions, metav1.Condition{
+		Type:    ConditionTypeKeysMissing,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: fmt.Sprintf("The files of the following keys are missing from the provider response; %s.", strings.Join(details, "; ")),
+	})
+}
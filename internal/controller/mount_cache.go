@@ -0,0 +1,236 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"container/list"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+)
+
+// mountCacheKey identifies the Mount calls which return the same files: the
+// same generation of a SecretProviderClass mounted with the token of the same
+// service account, the same provider secret if any, and the same object
+// versions sent to the provider.
+type mountCacheKey struct {
+	provider                      string
+	spcUID                        types.UID
+	spcGeneration                 int64
+	namespace                     string
+	serviceAccount                string
+	providerSecretUID             types.UID
+	providerSecretResourceVersion string
+	objectVersions                string
+}
+
+// objectVersionsKey returns the object versions in a form usable in a
+// mountCacheKey.
+func objectVersionsKey(objectVersions map[string]string) string {
+	var b strings.Builder
+	for _, id := range slices.Sorted(maps.Keys(objectVersions)) {
+		fmt.Fprintf(&b, "%q=%q;", id, objectVersions[id])
+	}
+	return b.String()
+}
+
+// mountResult is the response of a Mount call.
+type mountResult struct {
+	objectVersions map[string]string
+	files          map[string][]byte
+}
+
+// size returns the number of bytes of the files of the result.
+func (m mountResult) size() int {
+	size := 0
+	for path, contents := range m.files {
+		size += len(path) + len(contents)
+	}
+	return size
+}
+
+// clone returns a deep copy of the result, so that zeroing the cached files
+// never affects the result of a caller.
+func (m mountResult) clone() mountResult {
+	files := make(map[string][]byte, len(m.files))
+	for path, contents := range m.files {
+		files[path] = append([]byte(nil), contents...)
+	}
+	return mountResult{objectVersions: maps.Clone(m.objectVersions), files: files}
+}
+
+// zero overwrites the contents of the files, so secret values do not linger in
+// memory after they are evicted.
+func (m mountResult) zero() {
+	for _, contents := range m.files {
+		clear(contents)
+	}
+}
+
+type mountCacheEntry struct {
+	key     mountCacheKey
+	result  mountResult
+	size    int
+	expires time.Time
+	// timer evicts the entry when it expires
+	timer clock.Timer
+}
+
+// mountCall is a Mount call in flight, shared by the callers with the same key.
+type mountCall struct {
+	done   chan struct{}
+	result mountResult
+	reason string
+	err    error
+	// refs is the number of callers which did not copy the result yet, the
+	// last one zeroes it
+	refs int
+}
+
+// MountCache deduplicates the Mount calls of the SecretSyncs sharing a
+// SecretProviderClass and a service account: concurrent calls with the same
+// key share a single call to the provider, and its response is reused for the
+// TTL. The cached files are bounded by a maximum number of bytes, the least
+// recently used ones are evicted first, and every one is evicted and zeroed
+// when it expires. Errors are never cached.
+type MountCache struct {
+	ttl      time.Duration
+	maxBytes int
+	clock    clock.WithDelayedExecution
+
+	// lock guards the fields below
+	lock    sync.Mutex
+	entries map[mountCacheKey]*list.Element
+	lru     *list.List
+	size    int
+	calls   map[mountCacheKey]*mountCall
+}
+
+// NewMountCache returns a cache keeping the Mount responses for the TTL, up
+// to maxBytes of files.
+func NewMountCache(ttl time.Duration, maxBytes int) *MountCache {
+	return &MountCache{
+		ttl:      ttl,
+		maxBytes: maxBytes,
+		clock:    clock.RealClock{},
+		entries:  make(map[mountCacheKey]*list.Element),
+		lru:      list.New(),
+		calls:    make(map[mountCacheKey]*mountCall),
+	}
+}
+
+// do returns a copy of the cached result of the key, of the result of the
+// call in flight for the key, or of the result of mount. It reports whether
+// the provider call was saved. A nil cache always calls mount.
+func (c *MountCache) do(key mountCacheKey, mount func() (mountResult, string, error)) (_ mountResult, hit bool, reason string, err error) {
+	if c == nil {
+		result, reason, err := mount()
+		return result, false, reason, err
+	}
+
+	c.lock.Lock()
+	if element, ok := c.entries[key]; ok && !c.clock.Now().Before(element.Value.(*mountCacheEntry).expires) {
+		// expired, its timer did not run yet
+		c.remove(element)
+	}
+	if element, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(element)
+		result := element.Value.(*mountCacheEntry).result.clone()
+		c.lock.Unlock()
+		return result, true, "", nil
+	}
+	call, ok := c.calls[key]
+	if ok {
+		call.refs++
+	} else {
+		call = &mountCall{done: make(chan struct{}), refs: 1}
+		c.calls[key] = call
+	}
+	c.lock.Unlock()
+
+	if !ok {
+		call.result, call.reason, call.err = mount()
+
+		c.lock.Lock()
+		delete(c.calls, key)
+		if call.err == nil {
+			c.add(key, call.result.clone())
+		}
+		c.lock.Unlock()
+		close(call.done)
+	} else {
+		<-call.done
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var result mountResult
+	if call.err == nil {
+		result = call.result.clone()
+	}
+	if call.refs--; call.refs == 0 {
+		call.result.zero()
+	}
+	return result, ok, call.reason, call.err
+}
+
+// add caches the result unless it is larger than the cache, evicting the
+// least recently used entries to make room for it.
+func (c *MountCache) add(key mountCacheKey, result mountResult) {
+	size := result.size()
+	if size > c.maxBytes {
+		result.zero()
+		return
+	}
+	for c.size+size > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+	entry := &mountCacheEntry{
+		key:     key,
+		result:  result,
+		size:    size,
+		expires: c.clock.Now().Add(c.ttl),
+	}
+	element := c.lru.PushFront(entry)
+	entry.timer = c.clock.AfterFunc(c.ttl, func() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		if c.entries[key] == element {
+			entry.timer = nil
+			c.remove(element)
+		}
+	})
+	c.entries[key] = element
+	c.size += size
+}
+
+// remove evicts an entry and zeroes its files.
+func (c *MountCache) remove(element *list.Element) {
+	entry := c.lru.Remove(element).(*mountCacheEntry)
+	if entry.timer != nil {
+		entry.timer.Stop()
+	}
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+	entry.result.zero()
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestMountCache(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Now())
+	c := NewMountCache(time.Minute, 12)
+	c.clock = clock
+
+	var calls int
+	mount := func(contents string) func() (mountResult, string, error) {
+		return func() (mountResult, string, error) {
+			calls++
+			return mountResult{
+				objectVersions: map[string]string{"foo": "v1"},
+				files:          map[string][]byte{"foo": []byte(contents)},
+			}, "", nil
+		}
+	}
+	keyA := mountCacheKey{provider: "fake-provider", spcUID: "a", namespace: "default", serviceAccount: "default"}
+	keyB := mountCacheKey{provider: "fake-provider", spcUID: "b", namespace: "default", serviceAccount: "default"}
+
+	result, hit, _, err := c.do(keyA, mount("aaa"))
+	assert.NoError(t, err)
+	assert.False(t, hit)
+	assert.Equal(t, map[string][]byte{"foo": []byte("aaa")}, result.files)
+
+	// the cached result is a copy
+	clear(result.files["foo"])
+	result, hit, _, err = c.do(keyA, mount("changed"))
+	assert.NoError(t, err)
+	assert.True(t, hit)
+	assert.Equal(t, map[string][]byte{"foo": []byte("aaa")}, result.files)
+	assert.Equal(t, map[string]string{"foo": "v1"}, result.objectVersions)
+	assert.Equal(t, 1, calls)
+
+	// a new generation of the SecretProviderClass is not cached yet
+	keyA2 := keyA
+	keyA2.spcGeneration = 2
+	_, hit, _, _ = c.do(keyA2, mount("aa2"))
+	assert.False(t, hit)
+
+	// the least recently used entry is evicted and zeroed to make room
+	cached := c.entries[keyA].Value.(*mountCacheEntry).result.files["foo"]
+	_, hit, _, _ = c.do(keyB, mount("bbb"))
+	assert.False(t, hit)
+	assert.NotContains(t, c.entries, keyA)
+	assert.Equal(t, []byte{0, 0, 0}, cached)
+	assert.Equal(t, 12, c.size)
+
+	// a result larger than the cache is not cached
+	_, _, _, _ = c.do(keyA, mount("larger than the cache"))
+	assert.NotContains(t, c.entries, keyA)
+
+	// expired entries are evicted and zeroed without a new lookup
+	cached = c.entries[keyB].Value.(*mountCacheEntry).result.files["foo"]
+	clock.Step(time.Minute)
+	assert.Empty(t, c.entries)
+	assert.Equal(t, 0, c.lru.Len())
+	assert.Equal(t, 0, c.size)
+	assert.Equal(t, []byte{0, 0, 0}, cached)
+	_, hit, _, _ = c.do(keyB, mount("bb2"))
+	assert.False(t, hit)
+
+	// errors are not cached
+	calls = 0
+	keyC := mountCacheKey{provider: "fake-provider", spcUID: "c"}
+	fail := func() (mountResult, string, error) {
+		calls++
+		return mountResult{}, ConditionReasonFailedProviderError, errors.New("provider error")
+	}
+	_, _, reason, err := c.do(keyC, fail)
+	assert.EqualError(t, err, "provider error")
+	assert.Equal(t, ConditionReasonFailedProviderError, reason)
+	_, _, _, err = c.do(keyC, fail)
+	assert.Error(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestMountCacheObjectVersions(t *testing.T) {
+	c := NewMountCache(time.Minute, 1<<20)
+	mount := func() (mountResult, string, error) {
+		return mountResult{files: map[string][]byte{"foo": []byte("foo")}}, "", nil
+	}
+	key := func(objectVersions map[string]string) mountCacheKey {
+		return mountCacheKey{provider: "fake-provider", spcUID: "a", objectVersions: objectVersionsKey(objectVersions)}
+	}
+
+	_, hit, _, _ := c.do(key(nil), mount)
+	assert.False(t, hit)
+
+	// a call sending other object versions may get another response
+	_, hit, _, _ = c.do(key(map[string]string{"foo": "v1", "bar": "v1"}), mount)
+	assert.False(t, hit)
+	_, hit, _, _ = c.do(key(map[string]string{"bar": "v1", "foo": "v1"}), mount)
+	assert.True(t, hit)
+	_, hit, _, _ = c.do(key(map[string]string{"foo": "v2", "bar": "v1"}), mount)
+	assert.False(t, hit)
+}
+
+func TestMountCacheSharesCallsInFlight(t *testing.T) {
+	c := NewMountCache(time.Minute, 1<<20)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var calls int
+	var leader mountResult
+	mount := func() (mountResult, string, error) {
+		calls++
+		close(started)
+		<-release
+		leader = mountResult{files: map[string][]byte{"foo": []byte("foo")}}
+		return leader, "", nil
+	}
+	key := mountCacheKey{provider: "fake-provider", spcUID: "a"}
+
+	var wg sync.WaitGroup
+	results := make([]mountResult, 5)
+	hits := make([]bool, 5)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0], hits[0], _, _ = c.do(key, mount)
+	}()
+	<-started
+	for i := 1; i < len(results); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], hits[i], _, _ = c.do(key, mount)
+		}()
+	}
+	// wait for the other callers to join the call in flight
+	for {
+		c.lock.Lock()
+		refs := c.calls[key].refs
+		c.lock.Unlock()
+		if refs == len(results) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []bool{false, true, true, true, true}, hits)
+	for _, result := range results {
+		assert.Equal(t, map[string][]byte{"foo": []byte("foo")}, result.files)
+	}
+	// the response of the provider is zeroed once every caller copied it
+	assert.Equal(t, []byte{0, 0, 0}, leader.files["foo"])
+}
+
+func TestNilMountCache(t *testing.T) {
+	var c *MountCache
+	var calls int
+	for range 2 {
+		_, hit, _, err := c.do(mountCacheKey{}, func() (mountResult, string, error) {
+			calls++
+			return mountResult{}, "", nil
+		})
+		assert.NoError(t, err)
+		assert.False(t, hit)
+	}
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsyncv1alpha1 "sigs.k8s.io/secrets-store-sync-controller/api/v1alpha1"
+)
+
+const (
+	// ProviderSecretLabelKey and ProviderSecretLabelValue label the secrets
+	// which may be referenced as provider credentials, as required by the
+	// Secrets Store CSI Driver for the nodePublishSecretRef secrets.
+	ProviderSecretLabelKey   = "secrets-store.csi.k8s.io/used"
+	ProviderSecretLabelValue = "true"
+)
+
+// getProviderSecret returns the secret referenced by spec.providerSecretRef,
+// nil if the SecretSync does not reference one. The secrets holding provider
+// credentials are not managed by the controller and therefore not cached, they
+// are read from the API server.
+func (r *SecretSyncReconciler) getProviderSecret(ctx context.Context, ss *secretsyncv1alpha1.SecretSync) (*corev1.Secret, string, error) {
+	if ss.Spec.ProviderSecretRef == nil {
+		return nil, "", nil
+	}
+
+	name := ss.Spec.ProviderSecretRef.Name
+	secret, err := r.Clientset.CoreV1().Secrets(ss.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ConditionReasonProviderSecretError, fmt.Errorf("provider secret %q does not exist in namespace %q", name, ss.Namespace)
+		}
+		return nil, ConditionReasonProviderSecretError, fmt.Errorf("failed to get provider secret %q: %w", name, err)
+	}
+	if secret.Labels[ProviderSecretLabelKey] != ProviderSecretLabelValue {
+		return nil, ConditionReasonProviderSecretError, fmt.Errorf("provider secret %q must be labeled %s=%s", name, ProviderSecretLabelKey, ProviderSecretLabelValue)
+	}
+	return secret, "", nil
+}
+
+// providerSecretData returns the data of the provider secret in the format
+// of the Mount secrets.
+func providerSecretData(secret *corev1.Secret) map[string]string {
+	data := make(map[string]string)
+	if secret == nil {
+		return data
+	}
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+	return data
+}
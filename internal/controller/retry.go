@@ -0,0 +1,216 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	secretsyncv1alpha1 "sigs.k8s.io/secrets-store-sync-controller/api/v1alpha1"
+)
+
+const (
+	// DefaultMinRetryBackoff and DefaultMaxRetryBackoff bound the backoff of
+	// the retries of the failed syncs by default.
+	DefaultMinRetryBackoff = time.Second
+	DefaultMaxRetryBackoff = 5 * time.Minute
+)
+
+var (
+	// permanentFailureReasons are the reasons of the failures which are only
+	// retried at the next rotation of the SecretSync, or once its spec, its
+	// SecretProviderClass or the discovered providers change.
+	permanentFailureReasons = sets.New(
+		ConditionReasonFailedInvalidLabelError,
+		ConditionReasonFailedInvalidAnnotationError,
+		ConditionReasonInvalidRotation,
+		ConditionReasonControllerSpcError,
+		ConditionReasonProviderNotFound,
+	)
+
+	// transientCodes are the gRPC codes of the provider errors which are
+	// retried with a backoff, the other codes, e.g. PermissionDenied or
+	// NotFound, likely need a change of the SecretProviderClass or of the
+	// secret store.
+	transientCodes = sets.New(
+		codes.Unknown,
+		codes.Canceled,
+		codes.DeadlineExceeded,
+		codes.ResourceExhausted,
+		codes.Aborted,
+		codes.Internal,
+		codes.Unavailable,
+	)
+)
+
+// RetryBackoff bounds the exponential backoff of the retries of the syncs
+// failing with a transient error.
+type RetryBackoff struct {
+	Min, Max time.Duration
+}
+
+// bounds returns the bounds of the backoff, the defaults if unset.
+func (b RetryBackoff) bounds() (time.Duration, time.Duration) {
+	minDelay, maxDelay := b.Min, b.Max
+	if minDelay <= 0 {
+		minDelay = DefaultMinRetryBackoff
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultMaxRetryBackoff
+	}
+	return minDelay, max(minDelay, maxDelay)
+}
+
+// delay returns the delay before retrying a sync which failed failureCount
+// times in a row.
+func (b RetryBackoff) delay(failureCount int32) time.Duration {
+	minDelay, maxDelay := b.bounds()
+	delay := minDelay
+	for i := int32(1); i < failureCount && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// isTransientFailure returns true if a sync which failed with the reason and
+// the error should be retried with a backoff.
+func isTransientFailure(reason string, err error) bool {
+	// the provider errors, by gRPC code
+	if s, ok := status.FromError(err); ok && s.Code() != codes.OK {
+		return transientCodes.Has(s.Code())
+	}
+
+	// the API server errors, e.g. of the TokenRequests or the secret patches
+	if apierrors.ReasonForError(err) != metav1.StatusReasonUnknown {
+		switch {
+		case apierrors.IsForbidden(err), // e.g. denied by an admission webhook or the RBAC
+			apierrors.IsInvalid(err), // e.g. denied by a validating admission policy
+			apierrors.IsBadRequest(err),
+			apierrors.IsRequestEntityTooLargeError(err):
+			return false
+		}
+		return true
+	}
+
+	return !permanentFailureReasons.Has(reason)
+}
+
+// syncFailed records a failed sync attempt in the conditions and the status of
+// the SecretSync, and returns the error to return from Reconcile. Transient
+// failures are retried with an exponential backoff, permanent ones at the next
+// rotation of the SecretSync, e.g. in case the permissions in the secret store
+// were fixed. Permanent failures of the SecretSyncs without rotation are
+// returned as terminal errors which are not retried.
+func (r *SecretSyncReconciler) syncFailed(ctx context.Context, ss *secretsyncv1alpha1.SecretSync, conditionType, reason, message string, err error) error {
+	now := r.Scheduler.now()
+	ss.Status.LastAttemptTime = &metav1.Time{Time: now}
+	ss.Status.FailureCount++
+	ss.Status.NextRetryTime = nil
+
+	var delay time.Duration
+	if isTransientFailure(reason, err) {
+		delay = r.RetryBackoff.delay(ss.Status.FailureCount)
+	} else if rotation, rotationErr := parseRotation(ss.Spec.Rotation, r.rotationPollInterval); rotationErr == nil && rotation != nil {
+		delay = rotation.next(now, rotationSpread(ss.UID)).Sub(now)
+	}
+	if delay > 0 {
+		ss.Status.NextRetryTime = &metav1.Time{Time: now.Add(delay)}
+		r.retryLimiter.retryAfter(reconcile.Request{NamespacedName: client.ObjectKeyFromObject(ss)}, delay)
+	}
+
+	r.updateStatusConditions(ctx, ss, conditionType, metav1.ConditionFalse, reason, message, true)
+	if delay == 0 {
+		return reconcile.TerminalError(err)
+	}
+	return err
+}
+
+// syncSucceeded records a successful sync attempt in the status of the
+// SecretSync.
+func (r *SecretSyncReconciler) syncSucceeded(ss *secretsyncv1alpha1.SecretSync) {
+	ss.Status.LastAttemptTime = &metav1.Time{Time: r.Scheduler.now()}
+	ss.Status.FailureCount = 0
+	ss.Status.NextRetryTime = nil
+}
+
+// retryRateLimiter requeues the SecretSyncs whose sync failed with a transient
+// error after the delay computed from their failure count by Reconcile, which
+// is kept in the status across restarts. The other errors back off
+// exponentially within the same bounds.
+type retryRateLimiter struct {
+	workqueue.TypedRateLimiter[reconcile.Request]
+
+	lock   sync.Mutex
+	delays map[reconcile.Request]time.Duration
+}
+
+func newRetryRateLimiter(backoff RetryBackoff) *retryRateLimiter {
+	minDelay, maxDelay := backoff.bounds()
+	return &retryRateLimiter{
+		TypedRateLimiter: workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](minDelay, maxDelay),
+		delays:           make(map[reconcile.Request]time.Duration),
+	}
+}
+
+// retryAfter sets the delay of the next retry of the request.
+func (l *retryRateLimiter) retryAfter(req reconcile.Request, delay time.Duration) {
+	if l == nil {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.delays[req] = delay
+}
+
+// Forget drops the delay of the next retry of the request along with its
+// failures, once its sync succeeded or failed for good, or once the SecretSync
+// is deleted or handled by another controller.
+func (l *retryRateLimiter) Forget(req reconcile.Request) {
+	if l == nil {
+		return
+	}
+
+	l.lock.Lock()
+	delete(l.delays, req)
+	l.lock.Unlock()
+	l.TypedRateLimiter.Forget(req)
+}
+
+func (l *retryRateLimiter) When(req reconcile.Request) time.Duration {
+	l.lock.Lock()
+	delay, ok := l.delays[req]
+	delete(l.delays, req)
+	l.lock.Unlock()
+
+	// keep counting the failures of the fallback backoff
+	fallback := l.TypedRateLimiter.When(req)
+	if ok {
+		return delay
+	}
+	return fallback
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestIsTransientFailure(t *testing.T) {
+	tokenRequests := schema.GroupResource{Resource: "serviceaccounts/token"}
+
+	tests := []struct {
+		name      string
+		reason    string
+		err       error
+		transient bool
+	}{
+		{
+			name:      "provider unavailable",
+			reason:    ConditionReasonFailedProviderError,
+			err:       status.Error(codes.Unavailable, "connection refused"),
+			transient: true,
+		},
+		{
+			name:      "wrapped provider timeout",
+			reason:    ConditionReasonFailedProviderError,
+			err:       fmt.Errorf("failed to mount: %w", status.Error(codes.DeadlineExceeded, "timeout")),
+			transient: true,
+		},
+		{
+			name:   "provider permission denied",
+			reason: ConditionReasonFailedProviderError,
+			err:    status.Error(codes.PermissionDenied, "access denied"),
+		},
+		{
+			name:   "provider object not found",
+			reason: ConditionReasonFailedProviderError,
+			err:    status.Error(codes.NotFound, "no such secret"),
+		},
+		{
+			name:   "token request denied",
+			reason: ConditionReasonFailedProviderError,
+			err:    apierrors.NewForbidden(tokenRequests, "default", errors.New("denied")),
+		},
+		{
+			name:   "secret denied by an admission policy",
+			reason: ConditionReasonControllerPatchError,
+			err:    apierrors.NewInvalid(schema.GroupKind{Kind: "Secret"}, "sse2esecret", nil),
+		},
+		{
+			name:      "API server unavailable",
+			reason:    ConditionReasonControllerPatchError,
+			err:       apierrors.NewServiceUnavailable("try again"),
+			transient: true,
+		},
+		{
+			name:      "conflict",
+			reason:    ConditionReasonControllerPatchError,
+			err:       apierrors.NewConflict(schema.GroupResource{Resource: "secrets"}, "sse2esecret", errors.New("modified")),
+			transient: true,
+		},
+		{
+			name:   "invalid label",
+			reason: ConditionReasonFailedInvalidLabelError,
+			err:    errors.New("invalid label"),
+		},
+		{
+			name:   "invalid rotation",
+			reason: ConditionReasonInvalidRotation,
+			err:    errors.New("invalid rotation"),
+		},
+		{
+			name:      "other error",
+			reason:    ConditionReasonFailedProviderError,
+			err:       errors.New("failed"),
+			transient: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if transient := isTransientFailure(test.reason, test.err); transient != test.transient {
+				t.Fatalf("expected transient %t, got %t", test.transient, transient)
+			}
+		})
+	}
+}
+
+func TestRetryBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		backoff  RetryBackoff
+		failures int32
+		expected time.Duration
+	}{
+		{
+			name:     "first failure",
+			backoff:  RetryBackoff{Min: time.Second, Max: time.Minute},
+			failures: 1,
+			expected: time.Second,
+		},
+		{
+			name:     "doubled",
+			backoff:  RetryBackoff{Min: time.Second, Max: time.Minute},
+			failures: 4,
+			expected: 8 * time.Second,
+		},
+		{
+			name:     "capped",
+			backoff:  RetryBackoff{Min: time.Second, Max: time.Minute},
+			failures: 100,
+			expected: time.Minute,
+		},
+		{
+			name:     "defaults",
+			failures: 100,
+			expected: DefaultMaxRetryBackoff,
+		},
+		{
+			name:     "max below min",
+			backoff:  RetryBackoff{Min: time.Minute, Max: time.Second},
+			failures: 3,
+			expected: time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if delay := test.backoff.delay(test.failures); delay != test.expected {
+				t.Fatalf("expected %s, got %s", test.expected, delay)
+			}
+		})
+	}
+}
+
+func TestRetryRateLimiter(t *testing.T) {
+	limiter := newRetryRateLimiter(RetryBackoff{Min: time.Second, Max: time.Minute})
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "sse2esecret"}}
+
+	// the delay set by Reconcile is used once
+	limiter.retryAfter(req, 30*time.Second)
+	if delay := limiter.When(req); delay != 30*time.Second {
+		t.Fatalf("expected 30s, got %s", delay)
+	}
+
+	// the other errors back off exponentially
+	if delay := limiter.When(req); delay != 2*time.Second {
+		t.Fatalf("expected 2s, got %s", delay)
+	}
+	limiter.Forget(req)
+	if delay := limiter.When(req); delay != time.Second {
+		t.Fatalf("expected 1s, got %s", delay)
+	}
+
+	// the delay of a deleted SecretSync is dropped
+	limiter.retryAfter(req, 30*time.Second)
+	limiter.Forget(req)
+	if len(limiter.delays) != 0 {
+		t.Fatalf("expected no delay left, got %v", limiter.delays)
+	}
+	if delay := limiter.When(req); delay != time.Second {
+		t.Fatalf("expected 1s, got %s", delay)
+	}
+
+	// a nil limiter ignores the delays
+	var unset *retryRateLimiter
+	unset.retryAfter(req, time.Second)
+	unset.Forget(req)
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	secretsyncv1alpha1 "sigs.k8s.io/secrets-store-sync-controller/api/v1alpha1"
+)
+
+// minRotationInterval is the shortest rotation interval of a SecretSync, also
+// enforced by the CRD validation.
+const minRotationInterval = time.Minute
+
+// rotationSchedule returns the next sync time of a SecretSync after a time,
+// given the spread of the SecretSync.
+type rotationSchedule interface {
+	next(after time.Time, spread uint64) time.Time
+}
+
+// intervalSchedule syncs every interval, at the offset of the SecretSync in
+// the interval so that the SecretSyncs synced at once, e.g. on startup, are
+// spread over the interval.
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) next(after time.Time, spread uint64) time.Time {
+	interval := int64(s)
+	offset := int64(spread % uint64(interval))
+	delay := (offset - after.UnixNano()%interval + interval) % interval
+	if delay == 0 {
+		delay = interval
+	}
+	return after.Add(time.Duration(delay))
+}
+
+// rotation is the parsed spec.rotation of a SecretSync.
+type rotation struct {
+	schedule rotationSchedule
+	jitter   time.Duration
+}
+
+// parseRotation parses the rotation of a SecretSync, SecretSyncs without
+// rotation are synced every defaultInterval. It returns nil if the SecretSync
+// is not rotated.
+func parseRotation(spec *secretsyncv1alpha1.Rotation, defaultInterval time.Duration) (*rotation, error) {
+	if spec == nil {
+		if defaultInterval <= 0 {
+			return nil, nil
+		}
+		return &rotation{schedule: intervalSchedule(defaultInterval)}, nil
+	}
+
+	out := &rotation{}
+	if spec.Jitter != nil {
+		if spec.Jitter.Duration < 0 {
+			return nil, fmt.Errorf("rotation jitter %s must not be negative", spec.Jitter.Duration)
+		}
+		out.jitter = spec.Jitter.Duration
+	}
+	switch {
+	case spec.Interval != nil && len(spec.Schedule) > 0:
+		return nil, errors.New("exactly one of rotation interval or schedule must be set")
+	case spec.Interval != nil:
+		if spec.Interval.Duration < minRotationInterval {
+			return nil, fmt.Errorf("rotation interval %s must be at least %s", spec.Interval.Duration, minRotationInterval)
+		}
+		out.schedule = intervalSchedule(spec.Interval.Duration)
+	case len(spec.Schedule) > 0:
+		schedule, err := parseCronSchedule(spec.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rotation schedule %q: %w", spec.Schedule, err)
+		}
+		out.schedule = schedule
+	default:
+		return nil, errors.New("exactly one of rotation interval or schedule must be set")
+	}
+	return out, nil
+}
+
+// next returns the next sync time after now, delayed by the jitter of the
+// SecretSync.
+func (r *rotation) next(now time.Time, spread uint64) time.Time {
+	next := r.schedule.next(now, spread)
+	if r.jitter > 0 {
+		next = next.Add(time.Duration(spread % uint64(r.jitter)))
+	}
+	return next
+}
+
+// cronSchedule is a cron expression, every field is the bit set of the
+// matching values.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// the day of month and the day of week are OR'ed when both are restricted,
+	// as in the cron of Vixie.
+	domStar, dowStar bool
+}
+
+// cronField describes the values of a field of a cron expression.
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute = cronField{name: "minute", min: 0, max: 59}
+	cronHour   = cronField{name: "hour", min: 0, max: 23}
+	cronDom    = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is Sunday as well
+	cronDow = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+
+	cronShortcuts = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// parseCronSchedule parses a cron expression with the minute, hour, day of
+// month, month and day of week fields, evaluated in UTC.
+func parseCronSchedule(expression string) (*cronSchedule, error) {
+	if shortcut, ok := cronShortcuts[strings.ToLower(strings.TrimSpace(expression))]; ok {
+		expression = shortcut
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+
+	s := &cronSchedule{
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}
+	var err error
+	for _, f := range []struct {
+		field *cronField
+		value string
+		bits  *uint64
+	}{
+		{&cronMinute, fields[0], &s.minute},
+		{&cronHour, fields[1], &s.hour},
+		{&cronDom, fields[2], &s.dom},
+		{&cronMonth, fields[3], &s.month},
+		{&cronDow, fields[4], &s.dow},
+	} {
+		if *f.bits, err = f.field.parse(f.value); err != nil {
+			return nil, err
+		}
+	}
+	// fold Sunday as 7 into Sunday as 0
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+
+	// e.g. the 30th of February
+	if s.next(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC), 0).IsZero() {
+		return nil, errors.New("the schedule never matches")
+	}
+	return s, nil
+}
+
+// parse returns the bit set of the values of the field, a comma-separated list
+// of values, ranges or * with an optional step.
+func (f *cronField) parse(value string) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(value, ",") {
+		expr, stepExpr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepExpr); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q in the %s field", stepExpr, f.name)
+			}
+		}
+
+		low, high := f.min, f.max
+		if expr != "*" {
+			lowExpr, highExpr, isRange := strings.Cut(expr, "-")
+			var err error
+			if low, err = f.value(lowExpr); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = f.value(highExpr); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// a/n is a-max/n
+				high = f.max
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range %q in the %s field", expr, f.name)
+			}
+		}
+		for v := low; v <= high; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// value parses a single value of the field, a number or a name.
+func (f *cronField) value(value string) (int, error) {
+	if v, ok := f.names[strings.ToLower(value)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q in the %s field, expected %d-%d", value, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// next returns the first time matching the schedule after the given time, or
+// the zero time if none matches within five years. The SecretSyncs with the
+// same schedule are only spread by their jitter.
+func (s *cronSchedule) next(after time.Time, _ uint64) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + 5
+	for t.Year() <= limit {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			// skip to the next matching minute of the hour, if any
+			if rest := s.minute >> uint(t.Minute()); rest != 0 {
+				t = t.Add(time.Duration(bits.TrailingZeros64(rest)) * time.Minute)
+			} else {
+				t = t.Truncate(time.Hour).Add(time.Hour)
+			}
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
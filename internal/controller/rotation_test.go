@@ -0,0 +1,248 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsyncv1alpha1 "sigs.k8s.io/secrets-store-sync-controller/api/v1alpha1"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2024, time.January, 10, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		expression string
+		expected   time.Time
+	}{
+		{
+			name:       "every minute",
+			expression: "* * * * *",
+			expected:   time.Date(2024, time.January, 10, 10, 31, 0, 0, time.UTC),
+		},
+		{
+			name:       "nightly",
+			expression: "0 2 * * *",
+			expected:   time.Date(2024, time.January, 11, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "step",
+			expression: "*/20 * * * *",
+			expected:   time.Date(2024, time.January, 10, 10, 40, 0, 0, time.UTC),
+		},
+		{
+			name:       "list and range",
+			expression: "5,50 8-10 * * *",
+			expected:   time.Date(2024, time.January, 10, 10, 50, 0, 0, time.UTC),
+		},
+		{
+			name:       "day of week name",
+			expression: "0 0 * * sun",
+			expected:   time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "sunday as 7",
+			expression: "0 0 * * 7",
+			expected:   time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "day of month or day of week",
+			expression: "0 0 12 * mon",
+			expected:   time.Date(2024, time.January, 12, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "leap day",
+			expression: "0 0 29 feb *",
+			expected:   time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "shortcut",
+			expression: "@monthly",
+			expected:   time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := parseCronSchedule(test.expression)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if next := schedule.next(now, 0); !next.Equal(test.expected) {
+				t.Fatalf("expected %s, got %s", test.expected, next)
+			}
+		})
+	}
+}
+
+func TestParseCronScheduleErrors(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"* * * foo *",
+		"0 0 30 feb *",
+	} {
+		if _, err := parseCronSchedule(expression); err == nil {
+			t.Errorf("expected an error for %q", expression)
+		}
+	}
+}
+
+func TestParseRotation(t *testing.T) {
+	now := time.Date(2024, time.January, 10, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		rotation        *secretsyncv1alpha1.Rotation
+		defaultInterval time.Duration
+		expected        time.Time
+		expectedErr     bool
+	}{
+		{
+			name:            "default interval",
+			defaultInterval: time.Hour,
+			expected:        time.Date(2024, time.January, 10, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "no rotation",
+		},
+		{
+			name:            "interval",
+			rotation:        &secretsyncv1alpha1.Rotation{Interval: &metav1.Duration{Duration: 5 * time.Minute}},
+			defaultInterval: time.Hour,
+			expected:        now.Add(5 * time.Minute),
+		},
+		{
+			name:     "schedule",
+			rotation: &secretsyncv1alpha1.Rotation{Schedule: "@daily"},
+			expected: time.Date(2024, time.January, 11, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:        "interval too short",
+			rotation:    &secretsyncv1alpha1.Rotation{Interval: &metav1.Duration{Duration: time.Second}},
+			expectedErr: true,
+		},
+		{
+			name: "interval and schedule",
+			rotation: &secretsyncv1alpha1.Rotation{
+				Interval: &metav1.Duration{Duration: time.Hour},
+				Schedule: "@daily",
+			},
+			expectedErr: true,
+		},
+		{
+			name:        "neither interval nor schedule",
+			rotation:    &secretsyncv1alpha1.Rotation{},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid schedule",
+			rotation:    &secretsyncv1alpha1.Rotation{Schedule: "@sometimes"},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rotation, err := parseRotation(test.rotation, test.defaultInterval)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.expected.IsZero() {
+				if rotation != nil {
+					t.Fatalf("expected no rotation, got %v", rotation)
+				}
+				return
+			}
+			if next := rotation.next(now, 0); !next.Equal(test.expected) {
+				t.Fatalf("expected %s, got %s", test.expected, next)
+			}
+		})
+	}
+
+	// the jitter delays the sync by the spread of the SecretSync, modulo the
+	// jitter
+	rotation, err := parseRotation(&secretsyncv1alpha1.Rotation{
+		Schedule: "@hourly",
+		Jitter:   &metav1.Duration{Duration: time.Minute},
+	}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := time.Date(2024, time.January, 10, 11, 0, 30, 0, time.UTC)
+	if next := rotation.next(now, uint64(time.Minute+30*time.Second)); !next.Equal(expected) {
+		t.Fatalf("expected %s, got %s", expected, next)
+	}
+}
+
+func TestIntervalScheduleSpread(t *testing.T) {
+	now := time.Date(2024, time.January, 10, 10, 30, 0, 0, time.UTC)
+	schedule := intervalSchedule(time.Hour)
+
+	tests := []struct {
+		name     string
+		spread   uint64
+		expected time.Time
+	}{
+		{
+			name:     "offset later in the interval",
+			spread:   uint64(45 * time.Minute),
+			expected: time.Date(2024, time.January, 10, 10, 45, 0, 0, time.UTC),
+		},
+		{
+			name:     "offset earlier in the interval",
+			spread:   uint64(15 * time.Minute),
+			expected: time.Date(2024, time.January, 10, 11, 15, 0, 0, time.UTC),
+		},
+		{
+			name:     "offset now",
+			spread:   uint64(30 * time.Minute),
+			expected: time.Date(2024, time.January, 10, 11, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "spread above the interval",
+			spread:   uint64(3*time.Hour + 45*time.Minute),
+			expected: time.Date(2024, time.January, 10, 10, 45, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if next := schedule.next(now, test.spread); !next.Equal(test.expected) {
+				t.Fatalf("expected %s, got %s", test.expected, next)
+			}
+		})
+	}
+}
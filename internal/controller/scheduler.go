@@ -0,0 +1,185 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+)
+
+// RotationScheduler keeps track of the next sync time of every SecretSync and
+// caps the rate of the syncs calling the providers. A nil RotationScheduler
+// does not limit the rate.
+type RotationScheduler struct {
+	clock clock.Clock
+
+	// limiter is nil if the rate is not limited
+	limiter *rate.Limiter
+
+	lock      sync.Mutex
+	scheduled map[types.NamespacedName]time.Time
+	// reservations are the provider calls of the throttled SecretSyncs
+	reservations map[types.NamespacedName]*rate.Reservation
+}
+
+// rotationQueueDepth is the number of SecretSyncs in every state of the
+// rotation queue.
+type rotationQueueDepth struct {
+	// scheduled SecretSyncs wait for their next sync time
+	scheduled int
+	// due SecretSyncs passed their next sync time without being synced yet
+	due int
+	// throttled syncs wait for the provider calls rate limit
+	throttled int
+}
+
+// NewRotationScheduler returns a RotationScheduler allowing qps syncs calling
+// the providers per second, with bursts of burst syncs. A qps of 0 disables
+// the limit.
+func NewRotationScheduler(qps float64, burst int) *RotationScheduler {
+	return newRotationScheduler(qps, burst, clock.RealClock{})
+}
+
+func newRotationScheduler(qps float64, burst int, clock clock.Clock) *RotationScheduler {
+	s := &RotationScheduler{
+		clock:        clock,
+		scheduled:    make(map[types.NamespacedName]time.Time),
+		reservations: make(map[types.NamespacedName]*rate.Reservation),
+	}
+	if qps > 0 {
+		s.limiter = rate.NewLimiter(rate.Limit(qps), max(burst, 1))
+	}
+	return s
+}
+
+// now returns the current time of the scheduler clock.
+func (s *RotationScheduler) now() time.Time {
+	if s == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
+// schedule records the next sync time of a SecretSync, the zero time if it is
+// not rotated.
+func (s *RotationScheduler) schedule(key types.NamespacedName, next time.Time) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if next.IsZero() {
+		delete(s.scheduled, key)
+		return
+	}
+	s.scheduled[key] = next
+}
+
+// forget stops tracking a SecretSync, giving its reserved provider call back
+// to the next syncs.
+func (s *RotationScheduler) forget(key types.NamespacedName) {
+	s.schedule(key, time.Time{})
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if reservation, ok := s.reservations[key]; ok {
+		reservation.CancelAt(s.clock.Now())
+		delete(s.reservations, key)
+	}
+}
+
+// reserve returns the delay until the rate limit allows the sync of a
+// SecretSync to call the provider, zero if it may call it now. The call is
+// reserved for the throttled SecretSync, which is requeued after the delay
+// rather than blocking a worker.
+func (s *RotationScheduler) reserve(key types.NamespacedName) time.Duration {
+	if s == nil || s.limiter == nil {
+		return 0
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.clock.Now()
+	reservation, ok := s.reservations[key]
+	if !ok {
+		reservation = s.limiter.ReserveN(now, 1)
+	}
+	delay := reservation.DelayFrom(now)
+	if delay <= 0 {
+		delete(s.reservations, key)
+		return 0
+	}
+	s.reservations[key] = reservation
+	return delay
+}
+
+// throttledError is returned by the syncs throttled by the provider calls rate
+// limit.
+type throttledError struct {
+	delay time.Duration
+}
+
+func (e *throttledError) Error() string {
+	return fmt.Sprintf("provider calls rate limit exceeded, retrying in %s", e.delay)
+}
+
+// depth returns the number of SecretSyncs in every state of the rotation
+// queue.
+func (s *RotationScheduler) depth() rotationQueueDepth {
+	if s == nil {
+		return rotationQueueDepth{}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.clock.Now()
+	depth := rotationQueueDepth{}
+	for _, reservation := range s.reservations {
+		if reservation.DelayFrom(now) > 0 {
+			depth.throttled++
+		}
+	}
+	for _, next := range s.scheduled {
+		if next.After(now) {
+			depth.scheduled++
+		} else {
+			depth.due++
+		}
+	}
+	return depth
+}
+
+// rotationSpread returns the spread of a SecretSync, which sets its offset in
+// the rotation interval and its jitter. It is derived from the UID so that the
+// SecretSyncs are spread evenly and keep their offset across restarts.
+func rotationSpread(uid types.UID) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(uid))
+	return h.Sum64()
+}
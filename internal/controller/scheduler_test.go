@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"k8s.io/apimachinery/pkg/types"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestRotationSchedulerRateLimit(t *testing.T) {
+	clock := clocktesting.NewFakeClock(time.Date(2024, time.January, 10, 10, 30, 0, 0, time.UTC))
+	scheduler := newRotationScheduler(1, 2, clock)
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Namespace: "default", Name: name}
+	}
+
+	// the burst is allowed at once
+	for _, name := range []string{"a", "b"} {
+		if delay := scheduler.reserve(key(name)); delay != 0 {
+			t.Fatalf("expected no delay, got %s", delay)
+		}
+	}
+
+	// the next sync is requeued once its reserved call is allowed
+	if delay := scheduler.reserve(key("c")); delay != time.Second {
+		t.Fatalf("expected a delay of 1s, got %s", delay)
+	}
+	if depth := scheduler.depth(); depth.throttled != 1 {
+		t.Fatalf("expected 1 throttled sync, got %d", depth.throttled)
+	}
+	clock.Step(500 * time.Millisecond)
+	if delay := scheduler.reserve(key("c")); delay != 500*time.Millisecond {
+		t.Fatalf("expected the remaining delay of 500ms, got %s", delay)
+	}
+	clock.Step(500 * time.Millisecond)
+	if delay := scheduler.reserve(key("c")); delay != 0 {
+		t.Fatalf("expected no delay, got %s", delay)
+	}
+	if depth := scheduler.depth(); depth.throttled != 0 {
+		t.Fatalf("expected no throttled sync, got %d", depth.throttled)
+	}
+
+	// a forgotten SecretSync gives its reserved call back
+	if delay := scheduler.reserve(key("d")); delay != time.Second {
+		t.Fatalf("expected a delay of 1s, got %s", delay)
+	}
+	scheduler.forget(key("d"))
+	if delay := scheduler.reserve(key("e")); delay != time.Second {
+		t.Fatalf("expected a delay of 1s, got %s", delay)
+	}
+
+	// a nil scheduler does not limit the rate
+	var unlimited *RotationScheduler
+	if delay := unlimited.reserve(key("a")); delay != 0 {
+		t.Fatalf("expected no delay, got %s", delay)
+	}
+	unlimited.forget(key("a"))
+}
+
+func TestRotationQueueDepth(t *testing.T) {
+	now := time.Date(2024, time.January, 10, 10, 30, 0, 0, time.UTC)
+	clock := clocktesting.NewFakeClock(now)
+	scheduler := newRotationScheduler(0, 0, clock)
+
+	reader := sdkmetric.NewManualReader()
+	statsReporter, err := newStatsReporter(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter(scope))
+	if err != nil {
+		t.Fatalf("unexpected stats reporter failure: %v", err)
+	}
+	statsReporter.rotationQueueDepth = scheduler.depth
+
+	expectDepth := func(expected map[string]int64) {
+		t.Helper()
+
+		depth := map[string]int64{}
+		for _, dp := range collectMetrics(t, reader)["rotation_queue_depth"].(metricdata.Gauge[int64]).DataPoints {
+			state, _ := dp.Attributes.Value(stateKey)
+			depth[state.AsString()] = dp.Value
+		}
+		for state, value := range expected {
+			if depth[state] != value {
+				t.Fatalf("expected rotation_queue_depth %v, got %v", expected, depth)
+			}
+		}
+	}
+
+	scheduler.schedule(types.NamespacedName{Namespace: "default", Name: "a"}, now.Add(time.Minute))
+	scheduler.schedule(types.NamespacedName{Namespace: "default", Name: "b"}, now.Add(time.Hour))
+	expectDepth(map[string]int64{stateScheduled: 2, stateDue: 0, stateThrottled: 0})
+
+	clock.Step(2 * time.Minute)
+	expectDepth(map[string]int64{stateScheduled: 1, stateDue: 1, stateThrottled: 0})
+
+	// synced again, and no longer rotated
+	scheduler.schedule(types.NamespacedName{Namespace: "default", Name: "a"}, time.Time{})
+	scheduler.forget(types.NamespacedName{Namespace: "default", Name: "b"})
+	expectDepth(map[string]int64{stateScheduled: 0, stateDue: 0, stateThrottled: 0})
+}
+
+func TestRotationSpread(t *testing.T) {
+	// the spread is stable across restarts, and differs between SecretSyncs
+	if rotationSpread("d6c1f0a2-0000-4000-8000-000000000001") != rotationSpread("d6c1f0a2-0000-4000-8000-000000000001") {
+		t.Fatalf("expected the same spread for the same UID")
+	}
+
+	schedule := intervalSchedule(12 * time.Hour)
+	now := time.Date(2024, time.January, 10, 10, 30, 0, 0, time.UTC)
+	seen := map[time.Time]bool{}
+	for _, uid := range []types.UID{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		next := schedule.next(now, rotationSpread(uid))
+		if !next.After(now) || next.After(now.Add(12*time.Hour)) {
+			t.Fatalf("expected the next sync within the interval, got %s", next)
+		}
+		seen[next] = true
+	}
+	if len(seen) < 8 {
+		t.Fatalf("expected the syncs to be spread, got %v", seen)
+	}
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	secretsyncv1alpha1 "sigs.k8s.io/secrets-store-sync-controller/api/v1alpha1"
+)
+
+const (
+	// EventReasonSecretDrifted is the reason of the event emitted when a managed
+	// secret was modified or deleted outside of the controller and got restored.
+	EventReasonSecretDrifted = "SecretDrifted"
+
+	// ManagedSecretLabelSelector selects the secrets managed by the
+	// controller, the only secrets cached and watched.
+	ManagedSecretLabelSelector = controllerLabelKey
+
+	driftTypeModified = "modified"
+	driftTypeDeleted  = "deleted"
+)
+
+// appliedSecret is the state of a secret as returned by the last apply of the
+// controller.
+type appliedSecret struct {
+	uid types.UID
+	// applyTime is the time of the managed fields entry of the controller,
+	// which only changes when an apply changes the secret.
+	applyTime metav1.Time
+	// fields are the fields owned by the controller after the apply.
+	fields string
+}
+
+func hasControllerLabel(obj client.Object) bool {
+	_, ok := obj.GetLabels()[controllerLabelKey]
+	return ok
+}
+
+// controllerApplyEntry returns the managed fields entry of the applies of the
+// controller in the secret, nil if there is none.
+func controllerApplyEntry(obj metav1.Object) *metav1.ManagedFieldsEntry {
+	entries := obj.GetManagedFields()
+	for i := range entries {
+		if entries[i].Manager == secretSyncControllerFieldManager && entries[i].Operation == metav1.ManagedFieldsOperationApply {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// controllerApplyTime returns the time of the managed fields entry of the
+// controller in the secret.
+func controllerApplyTime(obj metav1.Object) metav1.Time {
+	if entry := controllerApplyEntry(obj); entry != nil && entry.Time != nil {
+		return *entry.Time
+	}
+	return metav1.Time{}
+}
+
+// controllerApplyFields returns the fields owned by the applies of the
+// controller in the secret.
+func controllerApplyFields(obj metav1.Object) string {
+	if entry := controllerApplyEntry(obj); entry != nil && entry.FieldsV1 != nil {
+		return string(entry.FieldsV1.Raw)
+	}
+	return ""
+}
+
+// controllerDataKeys returns the keys of the data of the secret owned by the
+// applies of the controller.
+func controllerDataKeys(obj metav1.Object) []string {
+	fields := map[string]map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(controllerApplyFields(obj)), &fields); err != nil {
+		return nil
+	}
+	var keys []string
+	for field := range fields["f:data"] {
+		if key, ok := strings.CutPrefix(field, "f:"); ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// hasForeignDataFields returns true if field managers other than the
+// controller own keys of the data of the secret.
+func hasForeignDataFields(obj metav1.Object) bool {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager == secretSyncControllerFieldManager || entry.FieldsV1 == nil {
+			continue
+		}
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		if _, ok := fields["f:data"]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// managedSecretHandler enqueues the SecretSync controlling a managed secret.
+func managedSecretHandler(scheme *runtime.Scheme, mapper meta.RESTMapper) handler.EventHandler {
+	return handler.EnqueueRequestForOwner(scheme, mapper, &secretsyncv1alpha1.SecretSync{}, handler.OnlyControllerOwner())
+}
+
+// managedSecretPredicate filters the events of the managed secrets which may
+// be a drift: the deletion of a secret, or of its controller label which drops
+// it from the cache, and the changes made by other field managers. The
+// secrets created and changed by the controller itself are not requeued.
+func managedSecretPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(event.CreateEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if e.ObjectOld == nil || e.ObjectNew == nil {
+				return false
+			}
+			oldTime, newTime := controllerApplyTime(e.ObjectOld), controllerApplyTime(e.ObjectNew)
+			return oldTime.Equal(&newTime) &&
+				!apiequality.Semantic.DeepEqual(e.ObjectOld.GetManagedFields(), e.ObjectNew.GetManagedFields())
+		},
+		DeleteFunc: func(event.DeleteEvent) bool {
+			return true
+		},
+		GenericFunc: func(event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// cachedSecretDrift returns the drift of the secret of the SecretSync seen in
+// the cache of the managed secrets, which the watch of the secrets requeues
+// the SecretSync on. The secret is deleted if it is not cached, and possibly
+// modified if the fields of the controller changed since its last apply or,
+// without a previous apply, if other field managers set keys of its data. A
+// modification is only reported once the apply confirms it.
+func (r *SecretSyncReconciler) cachedSecretDrift(ctx context.Context, ss *secretsyncv1alpha1.SecretSync) (string, error) {
+	secretMeta := &metav1.PartialObjectMetadata{}
+	secretMeta.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+	if err := r.Get(ctx, client.ObjectKey{Namespace: ss.Namespace, Name: desiredSecretName(ss)}, secretMeta); err != nil {
+		if apierrors.IsNotFound(err) {
+			return driftTypeDeleted, nil
+		}
+		return "", err
+	}
+	if !hasControllerLabel(secretMeta) {
+		return driftTypeModified, nil
+	}
+
+	previous, ok := r.appliedSecret(client.ObjectKeyFromObject(ss))
+	if !ok {
+		if hasForeignDataFields(secretMeta) {
+			return driftTypeModified, nil
+		}
+		return "", nil
+	}
+	if previous.uid != secretMeta.UID {
+		return driftTypeDeleted, nil
+	}
+	// the cache may not have seen the last apply yet
+	applyTime := controllerApplyTime(secretMeta)
+	if applyTime.Equal(&previous.applyTime) && controllerApplyFields(secretMeta) != previous.fields {
+		return driftTypeModified, nil
+	}
+	return "", nil
+}
+
+// applySecret applies the secret of the SecretSync and returns the kind of
+// drift repaired by the apply, or an empty string if the secret was as the
+// controller left it.
+//
+// The drift is detected from the apply itself, the watch of the secrets only
+// requeues the SecretSync:
+//   - another field manager changed a key applied by the controller, the apply
+//     conflicts and is forced,
+//   - the secret was recreated, its UID changed since the last apply,
+//   - a field applied by the controller was removed, e.g. a key or the
+//     controller label, the apply changed the secret although unchanged was
+//     set.
+//
+// Keys and fields only set by other field managers are not a drift. The last
+// two checks need the previous apply of this controller instance, a secret
+// modified while the controller was not running is repaired but not reported.
+//
+// Without checkDrift the secret was not synchronized under its name before and
+// is claimed instead, see claimSecret.
+func (r *SecretSyncReconciler) applySecret(ctx context.Context, ss *secretsyncv1alpha1.SecretSync, datamap map[string][]byte, checkDrift, unchanged bool) (string, error) {
+	logger := log.FromContext(ctx)
+	key := client.ObjectKeyFromObject(ss)
+
+	driftType := ""
+	var secret *corev1.Secret
+	var err error
+	if checkDrift {
+		secret, err = r.serverSidePatchSecret(ctx, ss, datamap, "", false)
+		if apierrors.IsConflict(err) {
+			logger.V(4).Info("secret content is owned by other field managers", "secretName", desiredSecretName(ss), "conflict", err.Error())
+			driftType = driftTypeModified
+			secret, err = r.serverSidePatchSecret(ctx, ss, datamap, "", true)
+		}
+	} else {
+		secret, err = r.claimSecret(ctx, ss, datamap)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	applied := appliedSecret{uid: secret.UID, applyTime: controllerApplyTime(secret), fields: controllerApplyFields(secret)}
+	if previous, ok := r.appliedSecret(key); ok && checkDrift {
+		switch {
+		case previous.uid != applied.uid:
+			driftType = driftTypeDeleted
+		case len(driftType) == 0 && unchanged && !previous.applyTime.Equal(&applied.applyTime):
+			driftType = driftTypeModified
+		}
+	}
+	r.setAppliedSecret(key, &applied)
+	return driftType, nil
+}
+
+// appliedSecret returns the secret last applied for the SecretSync by this
+// controller instance.
+func (r *SecretSyncReconciler) appliedSecret(key types.NamespacedName) (appliedSecret, bool) {
+	r.syncedInputsLock.Lock()
+	defer r.syncedInputsLock.Unlock()
+	applied, ok := r.appliedSecrets[key]
+	return applied, ok
+}
+
+// setAppliedSecret records the secret applied for the SecretSync, nil forgets
+// the SecretSync.
+func (r *SecretSyncReconciler) setAppliedSecret(key types.NamespacedName, applied *appliedSecret) {
+	r.syncedInputsLock.Lock()
+	defer r.syncedInputsLock.Unlock()
+	if applied == nil {
+		delete(r.appliedSecrets, key)
+		return
+	}
+	if r.appliedSecrets == nil {
+		r.appliedSecrets = make(map[types.NamespacedName]appliedSecret)
+	}
+	r.appliedSecrets[key] = *applied
+}
+
+// reportSecretDrift records the repair of a secret which drifted.
+func (r *SecretSyncReconciler) reportSecretDrift(ctx context.Context, ss *secretsyncv1alpha1.SecretSync, secretName, driftType string) {
+	log.FromContext(ctx).Info("secret drifted from the desired state and was restored", "secretName", secretName, "driftType", driftType)
+	r.EventRecorder.Eventf(ss, corev1.EventTypeWarning, EventReasonSecretDrifted, "Secret %q was %s outside of the controller and has been restored", secretName, driftType)
+	r.statsReporter.reportSecretDrift(ctx, ss.Namespace, driftType)
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/csaupgrade"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsyncv1alpha1 "sigs.k8s.io/secrets-store-sync-controller/api/v1alpha1"
+)
+
+// desiredSecretName returns the name of the Kubernetes secret the SecretSync should
+// synchronize to.
+func desiredSecretName(ss *secretsyncv1alpha1.SecretSync) string {
+	if name := strings.TrimSpace(ss.Spec.SecretObject.Name); len(name) > 0 {
+		return name
+	}
+	return strings.TrimSpace(ss.Name)
+}
+
+// syncedSecretName returns the name of the Kubernetes secret the controller
+// last synchronized for the SecretSync, falling back to the desired name if the
+// SecretSync was never synchronized.
+func syncedSecretName(ss *secretsyncv1alpha1.SecretSync) string {
+	if len(ss.Status.SecretName) > 0 {
+		return ss.Status.SecretName
+	}
+	if len(ss.Status.SyncHash) > 0 {
+		// synchronized before status.secretName was recorded, the secret
+		// was always named after the SecretSync
+		return strings.TrimSpace(ss.Name)
+	}
+	return desiredSecretName(ss)
+}
+
+// getSecret reads a secret from the API server, the controller does not cache
+// secrets.
+func (r *SecretSyncReconciler) getSecret(ctx context.Context, namespace, name string) (*corev1.Secret, error) {
+	return r.Clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// secretSyncOwner returns the owner reference pointing to a SecretSync, if any.
+func secretSyncOwner(obj client.Object) *metav1.OwnerReference {
+	refs := obj.GetOwnerReferences()
+	for i := range refs {
+		gv, err := schema.ParseGroupVersion(refs[i].APIVersion)
+		if err != nil || gv.Group != secretsyncv1alpha1.GroupVersion.Group || refs[i].Kind != "SecretSync" {
+			continue
+		}
+		return &refs[i]
+	}
+	return nil
+}
+
+// errSecretNameConflict is returned when the secret the SecretSync
+// synchronizes to is managed by a different SecretSync.
+var errSecretNameConflict = errors.New("secret name conflict")
+
+// claimSecret creates or applies the secret the SecretSync synchronizes to under
+// a name it did not synchronize to before, never taking over a secret managed by
+// a different SecretSync. The SecretSyncs share the field manager, an apply
+// would silently replace the owner reference of another one, the claim relies
+// on the preconditions of the API server instead:
+//   - the secret is created, which fails if it exists,
+//   - an existing secret is applied without force at the version checked for
+//     its owner, which fails if it changed since.
+//
+// The created secret is handed over to the apply of the field manager, the
+// later applies would otherwise conflict with the fields set by the create.
+func (r *SecretSyncReconciler) claimSecret(ctx context.Context, ss *secretsyncv1alpha1.SecretSync, datamap map[string][]byte) (*corev1.Secret, error) {
+	name := desiredSecretName(ss)
+
+	created, err := r.Clientset.CoreV1().Secrets(ss.Namespace).Create(ctx, desiredSecret(ss, datamap), metav1.CreateOptions{FieldManager: secretSyncControllerFieldManager})
+	if err == nil {
+		patch, err := csaupgrade.UpgradeManagedFieldsPatch(created, sets.New(secretSyncControllerFieldManager), secretSyncControllerFieldManager)
+		if err != nil || patch == nil {
+			return created, err
+		}
+		return r.Clientset.CoreV1().Secrets(ss.Namespace).Patch(ctx, name, types.JSONPatchType, patch, metav1.PatchOptions{})
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return nil, err
+	}
+
+	secret, err := r.getSecret(ctx, ss.Namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if owner := secretSyncOwner(secret); owner != nil && owner.UID != ss.UID {
+		return nil, fmt.Errorf("%w: secret %q is already managed by SecretSync %q", errSecretNameConflict, name, owner.Name)
+	}
+	return r.serverSidePatchSecret(ctx, ss, datamap, secret.ResourceVersion, false)
+}
+
+// cleanupRenamedSecret handles the secret left behind after the target secret
+// name of the SecretSync changed. The previous secret is only touched if it is
+// still owned by the SecretSync, and is deleted or released according to the
+// deletion policy.
+func (r *SecretSyncReconciler) cleanupRenamedSecret(ctx context.Context, ss *secretsyncv1alpha1.SecretSync, previousName string) error {
+	logger := log.FromContext(ctx)
+
+	secret, err := r.getSecret(ctx, ss.Namespace, previousName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if owner := secretSyncOwner(secret); owner == nil || owner.UID != ss.UID {
+		logger.V(4).Info("previous secret is not owned by the SecretSync, leaving it in place", "secretName", previousName)
+		return nil
+	}
+
+	switch ss.Spec.DeletionPolicy {
+	case secretsyncv1alpha1.DeletionPolicyRetain:
+		return r.releaseSecret(ctx, ss, previousName, true)
+	case secretsyncv1alpha1.DeletionPolicyOrphan:
+		return r.releaseSecret(ctx, ss, previousName, false)
+	}
+
+	logger.Info("deleting previous secret after rename", "secretName", previousName)
+	err = r.Clientset.CoreV1().Secrets(ss.Namespace).Delete(ctx, previousName, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &secret.UID},
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete previous secret %q: %w", previousName, err)
+	}
+	return nil
+}
@@ -18,33 +18,45 @@ package controller
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"maps"
 	"os"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/pbkdf2"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	secretsstorecsiv1 "sigs.k8s.io/secrets-store-csi-driver/apis/v1"
@@ -80,6 +92,10 @@ const (
 	// secretSyncControllerFieldManager is the field manager used by the secrets store sync controller
 	secretSyncControllerFieldManager = "secrets-store-sync-controller"
 
+	// secretProviderClassNameIndexKey is the field index used to look up SecretSyncs
+	// referencing a given SecretProviderClass
+	secretProviderClassNameIndexKey = "spec.secretProviderClassName"
+
 	// Environment variables set using downward API to pass as params to the controller
 	// Used to maintain the same logic as the Secrets Store CSI driver
 	syncControllerPodName = "SYNC_CONTROLLER_POD_NAME"
@@ -90,6 +106,13 @@ type AllClientBuilder interface {
 	Get(ctx context.Context, provider string) (v1alpha1.CSIDriverProviderClient, error)
 }
 
+// ProvidersNotifier is implemented by the client builders which discover the
+// provider plugins, the SecretSyncs which failed because their provider was
+// not found are retried as soon as new providers are discovered.
+type ProvidersNotifier interface {
+	AddProvidersListener(listener provider.ProvidersListener)
+}
+
 // SecretSyncReconciler reconciles a SecretSync object
 type SecretSyncReconciler struct {
 	client.Client
@@ -99,26 +122,97 @@ type SecretSyncReconciler struct {
 	TokenCache      *token.Manager
 	ProviderClients AllClientBuilder
 	EventRecorder   record.EventRecorder
+
+	// MountCache shares the Mount responses between the SecretSyncs with the
+	// same SecretProviderClass and service account, nil if disabled.
+	MountCache *MountCache
+
+	// RetryBackoff bounds the backoff of the retries of the syncs failing with
+	// a transient error, the defaults if unset.
+	RetryBackoff RetryBackoff
+
+	// Scheduler tracks the next sync time of the SecretSyncs and caps the rate
+	// of the syncs calling the providers. If nil, the rate is not limited and
+	// the rotation queue is not reported.
+	Scheduler *RotationScheduler
+
+	// ControllerName is matched against spec.secretSyncControllerName of each
+	// SecretSync; objects addressed to a different controller are ignored.
+	ControllerName string
+
+	statsReporter *reporter
+
+	// retryLimiter requeues the SecretSyncs failing with a transient error at
+	// their next retry time.
+	retryLimiter *retryRateLimiter
+
+	// rotationPollInterval is the rotation interval of the SecretSyncs without
+	// spec.rotation, 0 if they are not rotated.
+	rotationPollInterval time.Duration
+
+	// lastSyncedInputs holds the sync inputs of the last successful sync of
+	// every SecretSync, to skip the state hash when the object versions
+	// returned by the provider did not change. appliedSecrets holds the
+	// secret last applied for every SecretSync, to detect its drift.
+	syncedInputsLock sync.Mutex
+	lastSyncedInputs map[types.NamespacedName]string
+	appliedSecrets   map[types.NamespacedName]appliedSecret
 }
 
-//+kubebuilder:rbac:groups=secret-sync.x-k8s.io,resources=secretsyncs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=secret-sync.x-k8s.io,resources=secretsyncs,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=secret-sync.x-k8s.io,resources=secretsyncs/finalizers,verbs=update
 //+kubebuilder:rbac:groups=secret-sync.x-k8s.io,resources=secretsyncs/status,verbs=get;update;patch
-//+kubebuilder:rbac:groups="",resources=secrets,verbs=create;patch
+// Only the metadata of the secrets labeled by the controller is listed and
+// watched, to requeue a SecretSync when its secret drifted. The other secrets
+// are only read by name: the provider credentials and the target secret of a
+// SecretSync, to keep the previous values of missing keys and to check its
+// owner before a takeover or a cleanup. The previous secret of a renamed
+// SecretSync is deleted with the Delete deletion policy.
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;create;patch;list;watch;delete
 //+kubebuilder:rbac:groups="",resources="serviceaccounts/token",verbs=create
-//+kubebuilder:rbac:groups="events.k8s.io",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 //+kubebuilder:rbac:groups=secrets-store.csi.x-k8s.io,resources=secretproviderclasses,verbs=get;list;watch
 
-func (r *SecretSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *SecretSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	ctx, span := startSpan(ctx, "Reconcile", req.NamespacedName)
+	defer func() { endSpan(span, err) }()
+
 	logger := log.FromContext(ctx)
 	logger.Info("Reconciling SecretSync", "namespace", req.NamespacedName.String())
+	start := time.Now()
 
 	// get the secret sync object
 	ss := &secretsyncv1alpha1.SecretSync{}
 	if err := r.Get(ctx, req.NamespacedName, ss); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.V(4).Info("SecretSync not found, it was deleted")
+			r.forgetSecretSync(req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
 		logger.Error(err, "unable to fetch SecretSync")
 		return ctrl.Result{}, err
 	}
 
+	if !r.isManagedByController(ss) {
+		logger.V(4).Info("SecretSync is handled by another controller, skipping", "secretSyncControllerName", ss.Spec.SecretSyncControllerName)
+		r.forgetSecretSync(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
+	if !ss.DeletionTimestamp.IsZero() {
+		if err := r.finalizeSecretSync(ctx, ss); err != nil {
+			logger.Error(err, "failed to finalize SecretSync", "deletionPolicy", ss.Spec.DeletionPolicy)
+			return ctrl.Result{}, err
+		}
+		r.forgetSecretSync(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.ensureFinalizer(ctx, ss); err != nil {
+		logger.Error(err, "failed to add finalizer to SecretSync")
+		return ctrl.Result{}, err
+	}
+
 	// if the secret sync hash is empty, it means the secret does not exist, so the condition type is create
 	// otherwise, the condition type is update
 	conditionType := ConditionTypeUpdate
@@ -133,51 +227,160 @@ func (r *SecretSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	}
 
-	secretName := strings.TrimSpace(ss.Name)
+	// Report the outcome of the sync by the reason of the resulting condition.
+	defer func() {
+		reason := ""
+		if condition := meta.FindStatusCondition(ss.Status.Conditions, conditionType); condition != nil {
+			reason = condition.Reason
+		}
+		r.statsReporter.reportSync(ctx, ss.Namespace, reason, time.Since(start))
+		span.SetAttributes(attribute.String("secretsync.reason", reason))
+		if ss.Status.LastSuccessfulSyncTime != nil {
+			r.statsReporter.setLastSuccessfulSync(req.NamespacedName, ss.Status.LastSuccessfulSyncTime.Time)
+		}
+	}()
+
+	secretName := desiredSecretName(ss)
 	secretObj := ss.Spec.SecretObject
 
 	reason, err := r.validateLabelsAnnotations(secretObj)
 	if err != nil {
-		r.updateStatusConditions(ctx, ss, conditionType, metav1.ConditionFalse, reason, err.Error(), true)
-		return ctrl.Result{}, err
+		return ctrl.Result{}, r.syncFailed(ctx, ss, conditionType, reason, err.Error(), err)
+	}
+
+	rotation, err := parseRotation(ss.Spec.Rotation, r.rotationPollInterval)
+	if err != nil {
+		return ctrl.Result{}, r.syncFailed(ctx, ss, conditionType, ConditionReasonInvalidRotation, err.Error(), err)
 	}
 
 	// get the secret provider class object
 	spc := &secretsstorecsiv1.SecretProviderClass{}
 	if err := r.Get(ctx, client.ObjectKey{Name: ss.Spec.SecretProviderClassName, Namespace: req.Namespace}, spc); err != nil {
+		if apierrors.IsNotFound(err) {
+			// the SecretProviderClass watch requeues this object once the class is (re)created
+			logger.Info("SecretProviderClass not found", "name", ss.Spec.SecretProviderClassName)
+			err = fmt.Errorf("SecretProviderClass %q does not exist in namespace %q", ss.Spec.SecretProviderClassName, req.Namespace)
+			return ctrl.Result{}, r.syncFailed(ctx, ss, conditionType, ConditionReasonControllerSpcError, err.Error(), err)
+		}
 		logger.Error(err, "failed to get SecretProviderClass", "name", ss.Spec.SecretProviderClassName)
-		r.updateStatusConditions(ctx, ss, conditionType, metav1.ConditionFalse, ConditionReasonControllerSpcError, fmt.Sprintf("failed to get SecretProviderClass %q: %v", ss.Spec.SecretProviderClassName, err), true)
-		return ctrl.Result{}, err
+		return ctrl.Result{}, r.syncFailed(ctx, ss, conditionType, ConditionReasonControllerSpcError, fmt.Sprintf("failed to get SecretProviderClass %q: %v", ss.Spec.SecretProviderClassName, err), err)
 	}
 
-	datamap, reason, err := r.fetchSecretsFromProvider(ctx, logger, spc, ss)
+	providerSecret, reason, err := r.getProviderSecret(ctx, ss)
 	if err != nil {
-		r.updateStatusConditions(ctx, ss, conditionType, metav1.ConditionFalse, reason, fmt.Sprintf("fetching secrets from the provider failed: %v", err), true)
-		return ctrl.Result{}, err
+		logger.Error(err, "failed to get provider secret")
+		return ctrl.Result{}, r.syncFailed(ctx, ss, conditionType, reason, err.Error(), err)
+	}
+
+	// Check if a secret create or update failed and if the controller should re-try the operation
+	var failedCondition *metav1.Condition
+	for _, ssCondition := range ss.Status.Conditions {
+		if FailedConditionsTriggeringRetry.Has(ssCondition.Reason) {
+			failedCondition = &ssCondition
+			break
+		}
+	}
+
+	// The target secret name changed since the last sync, the secret has to be
+	// created under the new name and the previous one cleaned up.
+	previousSecretName := syncedSecretName(ss)
+	renamed := previousSecretName != secretName
+
+	// A previously synced secret may have been modified or deleted behind the
+	// controller's back.
+	checkDrift := len(ss.Status.SyncHash) > 0 && !renamed
+	cachedDrift := ""
+	if checkDrift {
+		if cachedDrift, err = r.cachedSecretDrift(ctx, ss); err != nil {
+			logger.Error(err, "failed to get cached secret", "secretName", secretName)
+			return ctrl.Result{}, err
+		}
+	}
+
+	// The last sync succeeded with the same inputs and the next one is not due
+	// yet, e.g. after a restart of the controller: the provider is not called.
+	inputs := syncInputs(spc, ss, providerSecret)
+	inputsHash := computeInputsHash(inputs)
+	if next := ss.Status.NextSyncTime; next != nil && r.Scheduler.now().Before(next.Time) &&
+		failedCondition == nil && !renamed && len(cachedDrift) == 0 && ss.Status.InputsHash == inputsHash {
+		logger.V(4).Info("secret is up to date until the next sync", "secretName", secretName, "nextSyncTime", next)
+		r.Scheduler.schedule(req.NamespacedName, next.Time)
+		return ctrl.Result{RequeueAfter: next.Sub(r.Scheduler.now())}, nil
 	}
 
-	// Compute the hash of the secret
-	syncHash, err := computeCurrentStateHash(datamap, spc, ss)
+	datamap, objectVersions, missing, reason, err := r.fetchSecretsFromProvider(ctx, logger, spc, ss, providerSecret)
+	if throttled := (*throttledError)(nil); errors.As(err, &throttled) {
+		logger.V(4).Info("sync throttled by the provider calls rate limit", "retryAfter", throttled.delay)
+		return ctrl.Result{RequeueAfter: throttled.delay}, nil
+	}
 	if err != nil {
-		logger.Error(err, "failed to compute state hash", "secretName", secretName) // TODO: could this leak secrets?
-		r.updateStatusConditions(ctx, ss, conditionType, metav1.ConditionFalse, ConditionReasonControllerSyncError, "failed to compute state hash", true)
-		return ctrl.Result{}, err
+		return ctrl.Result{}, r.syncFailed(ctx, ss, conditionType, reason, fmt.Sprintf("fetching secrets from the provider failed: %v", err), err)
+	}
+	setMissingKeysCondition(ss, missing)
+	versionsChanged := !maps.Equal(objectVersions, ss.Status.ObjectVersions)
+
+	// The provider returned the object versions of the last sync of the same
+	// SecretProviderClass and SecretSync, the data is assumed unchanged: the
+	// expensive hash is skipped, and so is the apply unless the secret may have
+	// drifted, in which case the hash is only computed if the apply changes it.
+	syncHash := ss.Status.SyncHash
+	hashed := len(syncHash) == 0 || len(objectVersions) == 0 || versionsChanged || r.syncedInputs(req.NamespacedName) != inputs
+	if hashed {
+		// Compute the hash of the secret
+		if syncHash, err = computeCurrentStateHash(ctx, datamap, inputs, ss); err != nil {
+			logger.Error(err, "failed to compute state hash", "secretName", secretName) // TODO: could this leak secrets?
+			return ctrl.Result{}, r.syncFailed(ctx, ss, conditionType, ConditionReasonControllerSyncError, "failed to compute state hash", err)
+		}
+	} else {
+		logger.V(4).Info("object versions unchanged, skipping the state hash", "objectVersions", objectVersions)
 	}
 
 	// Check if the hash has changed.
 	hashChanged := syncHash != ss.Status.SyncHash
 
-	// Check if a secret create or update failed and if the controller should re-try the operation
-	var failedCondition *metav1.Condition
-	for _, ssCondition := range ss.Status.Conditions {
-		if slices.Contains(FailedConditionsTriggeringRetry, ssCondition.Reason) {
-			failedCondition = &ssCondition
-			break
+	driftType := ""
+	if failedCondition == nil && !hashChanged && !renamed && (hashed || len(cachedDrift) > 0) {
+		// the secret is applied again, which restores it if it drifted
+		driftType, err = r.applySecret(ctx, ss, datamap, checkDrift, true)
+		if err != nil {
+			logger.Error(err, "failed to patch secret", "secretName", secretName)
+			return ctrl.Result{}, r.syncFailed(ctx, ss, conditionType, ConditionReasonControllerPatchError, fmt.Sprintf("failed to patch secret %q: %v", secretName, err), err)
+		}
+		if len(driftType) == 0 && cachedDrift == driftTypeDeleted {
+			// not applied by this controller instance before
+			driftType = driftTypeDeleted
+		}
+		if driftType == driftTypeModified && !hashed {
+			// The apply changed the secret although the object versions did
+			// not change, the hash tells a drift from new provider content.
+			if syncHash, err = computeCurrentStateHash(ctx, datamap, inputs, ss); err != nil {
+				logger.Error(err, "failed to compute state hash", "secretName", secretName)
+				return ctrl.Result{}, r.syncFailed(ctx, ss, conditionType, ConditionReasonControllerSyncError, "failed to compute state hash", err)
+			}
+			hashChanged = syncHash != ss.Status.SyncHash
 		}
 	}
 
-	if failedCondition == nil && !hashChanged {
-		return ctrl.Result{}, nil
+	if failedCondition == nil && !hashChanged && !renamed {
+		if len(driftType) > 0 {
+			ss.Status.LastSuccessfulSyncTime = &metav1.Time{Time: time.Now()}
+		}
+
+		// the status records every attempt
+		result := r.scheduleNextSync(ss, rotation)
+		r.syncSucceeded(ss)
+		ss.Status.ObjectVersions = objectVersions
+		ss.Status.InputsHash = inputsHash
+		if err := r.Client.Status().Update(ctx, ss); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.setSyncedInputs(req.NamespacedName, inputs)
+		if len(driftType) > 0 {
+			r.reportSecretDrift(ctx, ss, secretName, driftType)
+		} else {
+			r.EventRecorder.Eventf(ss, corev1.EventTypeNormal, ConditionReasonSecretUpToDate, "Secret %q is up to date, no change detected", secretName)
+		}
+		return result, nil
 	}
 
 	if conditionType == ConditionTypeCreate {
@@ -190,31 +393,85 @@ func (r *SecretSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// Save current state for potential rollback.
 	prevSecretHash := ss.Status.SyncHash
 	prevTime := ss.Status.LastSuccessfulSyncTime
+	prevObjectVersions := ss.Status.ObjectVersions
 
 	// Update status fields.
 	ss.Status.LastSuccessfulSyncTime = &metav1.Time{Time: time.Now()}
 	ss.Status.SyncHash = syncHash
+	ss.Status.ObjectVersions = objectVersions
 
 	// Attempt to create or update the secret.
-	if err = r.serverSidePatchSecret(ctx, ss, datamap); err != nil {
+	driftType, err = r.applySecret(ctx, ss, datamap, checkDrift, false)
+	if err != nil {
 		logger.Error(err, "failed to patch secret", "secretName", secretName)
 
-		// Rollback to the previous hash and the previous last successful sync time.
+		// Rollback to the previous hash, the previous last successful sync time
+		// and the previous object versions.
 		ss.Status.SyncHash = prevSecretHash
 		ss.Status.LastSuccessfulSyncTime = prevTime
+		ss.Status.ObjectVersions = prevObjectVersions
 
-		r.updateStatusConditions(ctx, ss, conditionType, metav1.ConditionFalse, ConditionReasonControllerPatchError, fmt.Sprintf("failed to patch secret %q: %v", ss.Name, err), true)
-		return ctrl.Result{}, err
+		// Never take over a secret that another SecretSync synchronizes to.
+		if errors.Is(err, errSecretNameConflict) {
+			return ctrl.Result{}, r.syncFailed(ctx, ss, conditionType, ConditionReasonSecretNameConflict, err.Error(), err)
+		}
+		return ctrl.Result{}, r.syncFailed(ctx, ss, conditionType, ConditionReasonControllerPatchError, fmt.Sprintf("failed to patch secret %q: %v", secretName, err), err)
+	}
+
+	if len(driftType) == 0 && cachedDrift == driftTypeDeleted {
+		driftType = driftTypeDeleted
 	}
 
+	if renamed {
+		if err := r.cleanupRenamedSecret(ctx, ss, previousSecretName); err != nil {
+			logger.Error(err, "failed to clean up previous secret", "secretName", previousSecretName)
+			return ctrl.Result{}, err
+		}
+	}
+	ss.Status.SecretName = secretName
+	ss.Status.InputsHash = inputsHash
+	result := r.scheduleNextSync(ss, rotation)
+	r.syncSucceeded(ss)
+
 	// Update the status.
 	err = r.Client.Status().Update(ctx, ss)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	r.setSyncedInputs(req.NamespacedName, inputs)
+
+	switch {
+	case conditionType == ConditionTypeCreate:
+		r.EventRecorder.Eventf(ss, corev1.EventTypeNormal, ConditionReasonCreateSuccessful, "Secret %q created", secretName)
+	case hashChanged || renamed:
+		r.EventRecorder.Eventf(ss, corev1.EventTypeNormal, ConditionReasonSecretUpToDate, "Secret %q updated", secretName)
+	}
+
+	if len(driftType) > 0 {
+		r.reportSecretDrift(ctx, ss, secretName, driftType)
+	}
+
+	logger.V(4).Info("Done... updated status", "syncHash", syncHash, "lastSuccessfulSyncTime", ss.Status.LastSuccessfulSyncTime, "nextSyncTime", ss.Status.NextSyncTime)
+	return result, nil
+}
+
+// scheduleNextSync sets the next sync time of the SecretSync and returns the
+// result requeueing it at that time.
+func (r *SecretSyncReconciler) scheduleNextSync(ss *secretsyncv1alpha1.SecretSync, rotation *rotation) ctrl.Result {
+	var nextSyncTime *metav1.Time
+	var next time.Time
+	result := ctrl.Result{}
+	if rotation != nil {
+		now := r.Scheduler.now()
+		next = rotation.next(now, rotationSpread(ss.UID))
+		// the status only keeps seconds
+		nextSyncTime = &metav1.Time{Time: next.Truncate(time.Second)}
+		result.RequeueAfter = next.Sub(now)
+	}
+	r.Scheduler.schedule(client.ObjectKeyFromObject(ss), next)
 
-	logger.V(4).Info("Done... updated status", "syncHash", syncHash, "lastSuccessfulSyncTime", ss.Status.LastSuccessfulSyncTime)
-	return ctrl.Result{}, nil
+	ss.Status.NextSyncTime = nextSyncTime
+	return result
 }
 
 func (r *SecretSyncReconciler) validateLabelsAnnotations(
@@ -236,43 +493,123 @@ func (r *SecretSyncReconciler) fetchSecretsFromProvider(
 	logger logr.Logger,
 	spc *secretsstorecsiv1.SecretProviderClass,
 	ss *secretsyncv1alpha1.SecretSync,
-) (map[string][]byte, string, error) {
+	providerSecret *corev1.Secret,
+) (map[string][]byte, map[string]string, missingKeys, string, error) {
 	providerName := string(spc.Spec.Provider)
 	providerClient, err := r.ProviderClients.Get(ctx, providerName)
 	if err != nil {
 		logger.Error(err, "failed to get provider client", "provider", providerName)
-		return nil, ConditionReasonControllerSpcError, err
+		if errors.Is(err, provider.ErrProviderNotFound) {
+			// retried as soon as the provider socket is discovered
+			return nil, nil, missingKeys{}, ConditionReasonProviderNotFound, err
+		}
+		// e.g. the TLS credentials of a remote provider cannot be read yet
+		return nil, nil, missingKeys{}, ConditionReasonProviderUnavailable, err
 	}
 
-	paramsJSON, reason, err := r.prepareCSIProviderParams(logger, spc, ss.Namespace, ss.Spec.ServiceAccountName)
-	if err != nil {
-		return nil, reason, err
+	key := mountCacheKey{
+		provider:       providerName,
+		spcUID:         spc.UID,
+		spcGeneration:  spc.Generation,
+		namespace:      ss.Namespace,
+		serviceAccount: ss.Spec.ServiceAccountName,
+		// the provider may only return the objects changed since the sent
+		// versions
+		objectVersions: objectVersionsKey(ss.Status.ObjectVersions),
 	}
-
-	secretRefData := make(map[string]string)
-	var secretsJSON []byte
-	secretsJSON, err = json.Marshal(secretRefData)
-	if err != nil {
-		logger.Error(err, "failed to marshal secret")
-		return nil, ConditionReasonControllerSyncError, err
+	if providerSecret != nil {
+		// the responses are only shared between the same credentials
+		key.providerSecretUID = providerSecret.UID
+		key.providerSecretResourceVersion = providerSecret.ResourceVersion
 	}
+	result, hit, reason, err := r.MountCache.do(key, func() (mountResult, string, error) {
+		if delay := r.Scheduler.reserve(client.ObjectKeyFromObject(ss)); delay > 0 {
+			return mountResult{}, "", &throttledError{delay: delay}
+		}
 
-	oldObjectVersions := make(map[string]string)
-	_, files, err := provider.MountContent(ctx, providerClient, string(paramsJSON), string(secretsJSON), oldObjectVersions)
+		paramsJSON, reason, err := r.prepareCSIProviderParams(ctx, logger, spc, ss.Namespace, ss.Spec.ServiceAccountName)
+		if err != nil {
+			return mountResult{}, reason, err
+		}
+
+		secretRefData := providerSecretData(providerSecret)
+		var secretsJSON []byte
+		secretsJSON, err = json.Marshal(secretRefData)
+		if err != nil {
+			logger.Error(err, "failed to marshal secret")
+			return mountResult{}, ConditionReasonControllerSyncError, err
+		}
+
+		objectVersions, files, err := provider.MountContent(ctx, providerClient, providerName, string(paramsJSON), string(secretsJSON), ss.Status.ObjectVersions)
+		if err != nil {
+			logger.Error(err, "failed to get secrets from provider", "provider", providerName)
+			if errors.Is(err, provider.ErrProviderUnavailable) {
+				// the circuit breaker of the provider is open
+				return mountResult{}, ConditionReasonProviderUnavailable, err
+			}
+			return mountResult{}, ConditionReasonFailedProviderError, err
+		}
+		return mountResult{objectVersions: objectVersions, files: files}, "", nil
+	})
+	if r.MountCache != nil {
+		r.statsReporter.reportMountCacheRequest(ctx, providerName, hit)
+	}
 	if err != nil {
-		logger.Error(err, "failed to get secrets from provider", "provider", providerName)
-		return nil, ConditionReasonFailedProviderError, err
+		return nil, nil, missingKeys{}, reason, err
 	}
+	files := result.files
 
 	secretObj := ss.Spec.SecretObject
 	secretType := corev1.SecretType(secretObj.Type)
-	var datamap map[string][]byte
-	if datamap, err = secretutil.BuildKubeSecretData(secretObj.Data, secretType, files); err != nil {
-		logger.Error(err, "failed to get secret data", "secretName", ss.Name)
-		return nil, ConditionReasonRemoteSecretStoreFetchFailed, err
+	datamap, missingDataKeys, err := secretutil.BuildKubeSecretData(secretObj.Data, secretType, files, ss.Spec.MissingKeyPolicy)
+	if err != nil {
+		logger.Error(err, "failed to get secret data", "secretName", desiredSecretName(ss))
+		return nil, nil, missingKeys{}, ConditionReasonRemoteSecretStoreFetchFailed, err
+	}
+
+	if len(secretObj.DataFrom) > 0 {
+		selected, err := secretutil.BuildKubeSecretDataFrom(secretObj.DataFrom, secretType, files)
+		if err == nil {
+			err = mergeSecretData(datamap, selected, "secretObject.dataFrom")
+		}
+		if err != nil {
+			logger.Error(err, "failed to get secret data from dataFrom", "secretName", desiredSecretName(ss))
+			return nil, nil, missingKeys{}, ConditionReasonRemoteSecretStoreFetchFailed, err
+		}
+	}
+
+	if secretObj.Template != nil {
+		rendered, err := secretutil.RenderTemplateData(secretObj.Template.Data, files)
+		if err == nil {
+			err = mergeSecretData(datamap, rendered, "secretObject.template.data")
+		}
+		if err != nil {
+			logger.Error(err, "failed to render secret template", "secretName", desiredSecretName(ss))
+			return nil, nil, missingKeys{}, ConditionReasonSecretTemplateError, err
+		}
+	}
+
+	// the keys kept for the exploded entries are the ones no other field sets
+	missing, err := r.keepPreviousValues(ctx, ss, datamap, missingDataKeys)
+	if err != nil {
+		logger.Error(err, "failed to keep previous values of missing keys", "secretName", desiredSecretName(ss))
+		return nil, nil, missingKeys{}, ConditionReasonControllerSyncError, err
 	}
 
-	return datamap, "", nil
+	return datamap, result.objectVersions, missing, "", nil
+}
+
+// mergeSecretData adds the data built from the named field of the secret object
+// to datamap, refusing to overwrite keys that are already set.
+func mergeSecretData(datamap, data map[string][]byte, field string) error {
+	keys := slices.Sorted(maps.Keys(data))
+	for _, key := range keys {
+		if _, ok := datamap[key]; ok {
+			return fmt.Errorf("key %s in %s is already set", key, field)
+		}
+		datamap[key] = data[key]
+	}
+	return nil
 }
 
 // prepareCSIProviderPerams prepares the parameters that would normally be sent to
@@ -281,11 +618,18 @@ func (r *SecretSyncReconciler) fetchSecretsFromProvider(
 //
 // Returns JSON-serialized parameters, condition reason in case of an error, and the error itself.
 func (r *SecretSyncReconciler) prepareCSIProviderParams(
+	ctx context.Context,
 	logger logr.Logger,
 	spc *secretsstorecsiv1.SecretProviderClass,
 	namespace,
 	saName string,
-) ([]byte, string, error) {
+) (_ []byte, _ string, err error) {
+	_, span := otel.Tracer(scope).Start(ctx, "prepareCSIProviderParams", trace.WithAttributes(
+		attribute.String("namespace", namespace),
+		attribute.String("serviceaccount.name", saName),
+	))
+	defer func() { endSpan(span, err) }()
+
 	// get the service account token
 	serviceAccountTokenAttrs, err := token.SecretProviderServiceAccountTokenAttrs(r.TokenCache, namespace, saName, r.Audiences)
 	if err != nil {
@@ -313,9 +657,9 @@ func (r *SecretSyncReconciler) prepareCSIProviderParams(
 	return paramsJSON, "", nil
 }
 
-// serverSidePatchSecret performs a server-side patch on a Kubernetes Secret.
-// It updates the specified secret with the provided data, labels, and annotations.
-func (r *SecretSyncReconciler) serverSidePatchSecret(ctx context.Context, ss *secretsyncv1alpha1.SecretSync, datamap map[string][]byte) (err error) {
+// desiredSecret returns the Kubernetes secret the SecretSync synchronizes to,
+// with the provided data, labels, and annotations.
+func desiredSecret(ss *secretsyncv1alpha1.SecretSync, datamap map[string][]byte) *corev1.Secret {
 	// copy the object to make sure no code below mutates our cache
 	ssCopy := ss.DeepCopy()
 
@@ -325,47 +669,64 @@ func (r *SecretSyncReconciler) serverSidePatchSecret(ctx context.Context, ss *se
 	}
 	controllerLabels[controllerLabelKey] = ""
 
-	// Construct the patch for updating the Secret.
-	secretPatchData := corev1.Secret{
+	return &corev1.Secret{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Secret",
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:        ssCopy.Name,
+			Name:        desiredSecretName(ssCopy),
 			Namespace:   ssCopy.Namespace,
 			Labels:      controllerLabels,
 			Annotations: ssCopy.Spec.SecretObject.Annotations,
 			OwnerReferences: []metav1.OwnerReference{
 				{
-					APIVersion: ssCopy.APIVersion,
-					Kind:       ssCopy.Kind,
+					// the type meta of the SecretSync is not set by every client
+					APIVersion: secretsyncv1alpha1.GroupVersion.String(),
+					Kind:       "SecretSync",
 					Name:       ssCopy.Name,
 					UID:        ssCopy.UID,
+					// the finalizer of the SecretSync applies the deletion policy,
+					// blocking the owner deletion makes foreground deletion wait for the
+					// secret deleted by the Delete policy
+					Controller:         ptr.To(true),
+					BlockOwnerDeletion: ptr.To(blocksOwnerDeletion(ssCopy)),
 				},
 			},
 		},
 		Data: datamap,
 		Type: corev1.SecretType(ssCopy.Spec.SecretObject.Type),
 	}
+}
+
+// serverSidePatchSecret performs a server-side patch on a Kubernetes Secret.
+// It updates the specified secret with the provided data, labels, and annotations.
+// If resourceVersion is set, the patch fails if the secret changed since that
+// version. If force is set, conflicting fields owned by other field managers are
+// taken over. It returns the patched secret.
+func (r *SecretSyncReconciler) serverSidePatchSecret(ctx context.Context, ss *secretsyncv1alpha1.SecretSync, datamap map[string][]byte, resourceVersion string, force bool) (_ *corev1.Secret, err error) {
+	ctx, span := startSpan(ctx, "serverSidePatchSecret", client.ObjectKeyFromObject(ss))
+	defer func() { endSpan(span, err) }()
+
+	// Construct the patch for updating the Secret.
+	secretPatchData := desiredSecret(ss, datamap)
+	secretPatchData.ResourceVersion = resourceVersion
 
 	patchData, err := json.Marshal(secretPatchData)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Perform the server-side patch on the Secret.
-	_, err = r.Clientset.CoreV1().Secrets(secretPatchData.Namespace).Patch(ctx, secretPatchData.Name, types.ApplyPatchType, patchData, metav1.PatchOptions{FieldManager: secretSyncControllerFieldManager})
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return r.Clientset.CoreV1().Secrets(secretPatchData.Namespace).Patch(ctx, secretPatchData.Name, types.ApplyPatchType, patchData, metav1.PatchOptions{FieldManager: secretSyncControllerFieldManager, Force: ptr.To(force)})
 }
 
 // computeSecretDataObjectHash computes the HMAC hash of the provided secret data
 // using the SS UID as the key.
-func computeCurrentStateHash(secretData map[string][]byte, spc *secretsstorecsiv1.SecretProviderClass, ss *secretsyncv1alpha1.SecretSync) (string, error) {
+func computeCurrentStateHash(ctx context.Context, secretData map[string][]byte, inputs string, ss *secretsyncv1alpha1.SecretSync) (_ string, err error) {
+	_, span := startSpan(ctx, "computeCurrentStateHash", client.ObjectKeyFromObject(ss))
+	defer func() { endSpan(span, err) }()
+
 	// Serialize the secret data, parts of the spc and the ss data.
 	secretBytes, err := json.Marshal(secretData)
 	if err != nil {
@@ -375,31 +736,98 @@ func computeCurrentStateHash(secretData map[string][]byte, spc *secretsstorecsiv
 	// user-input base for the hashing below
 	secretBytesLenPrefixed := append([]byte(strconv.Itoa(len(secretBytes))+":"), secretBytes...)
 
-	// changes to any of the below inputs (and the above secret data) mean the state
+	// changes to any of the sync inputs (and the above secret data) mean the state
 	// changed and we should attempt a new sync
-	toHash := strings.Join(
-		[]string{
-			// SecretProviderClass bits
-			string(spc.UID), // SPC UID in case the SPC object got recreated
-			strconv.FormatInt(spc.ObjectMeta.Generation, 10), // SPC generation in case the current SPC object's spec changed (ignore status changes)
-			// SecretSync bits
-			string(ss.UID), // SS UID in case the SS got recreated
-			strconv.FormatInt(ss.ObjectMeta.Generation, 10), // SS generation in case the SS spec changed (ignore status changes)
-			// ForceSynchronization is the only user input in this group and must therefore always come last
-			ss.Spec.ForceSynchronization, // changes to this field should always cause a new attempt to sync the Secret
-		},
-		"|",
-	)
 
 	salt := []byte(string(ss.UID))
 	// we need to use key derivation here rather than hashing directly in case the
 	// secretBytes had low enthropy -> the rest of the hash input are discoverable
 	// and we could leak the secret otherwise.
-	dk := pbkdf2.Key(append(secretBytesLenPrefixed, []byte(toHash)...), salt, 100_000, 32, sha512.New)
+	dk := pbkdf2.Key(append(secretBytesLenPrefixed, []byte(inputs)...), salt, 100_000, 32, sha512.New)
 
 	return "v1:" + hex.EncodeToString(dk), nil
 }
 
+// syncInputs returns the inputs of a sync, besides the secret data, which
+// are covered by the state hash. None of them is confidential.
+func syncInputs(spc *secretsstorecsiv1.SecretProviderClass, ss *secretsyncv1alpha1.SecretSync, providerSecret *corev1.Secret) string {
+	inputs := []string{
+		// SecretProviderClass bits
+		string(spc.UID), // SPC UID in case the SPC object got recreated
+		strconv.FormatInt(spc.ObjectMeta.Generation, 10), // SPC generation in case the current SPC object's spec changed (ignore status changes)
+		// SecretSync bits
+		string(ss.UID), // SS UID in case the SS got recreated
+		strconv.FormatInt(ss.ObjectMeta.Generation, 10), // SS generation in case the SS spec changed (ignore status changes)
+	}
+	if providerSecret != nil {
+		// provider secret bits, only when referenced so the hash of the other
+		// SecretSyncs is unchanged
+		inputs = append(inputs,
+			string(providerSecret.UID),     // in case the provider secret got recreated
+			providerSecret.ResourceVersion, // in case the provider credentials changed
+		)
+	}
+	// ForceSynchronization is the only user input in this group and must therefore always come last
+	inputs = append(inputs, ss.Spec.ForceSynchronization) // changes to this field should always cause a new attempt to sync the Secret
+	return strings.Join(inputs, "|")
+}
+
+// computeInputsHash returns the hash of the sync inputs recorded in the status,
+// they hold no secret data.
+func computeInputsHash(inputs string) string {
+	sum := sha256.Sum256([]byte(inputs))
+	return hex.EncodeToString(sum[:])
+}
+
+// syncedInputs returns the sync inputs of the last successful sync of the
+// SecretSync by this controller instance, an empty string if unknown.
+func (r *SecretSyncReconciler) syncedInputs(key types.NamespacedName) string {
+	r.syncedInputsLock.Lock()
+	defer r.syncedInputsLock.Unlock()
+	return r.lastSyncedInputs[key]
+}
+
+// setSyncedInputs records the sync inputs of a successful sync, an empty
+// string forgets the SecretSync.
+func (r *SecretSyncReconciler) setSyncedInputs(key types.NamespacedName, inputs string) {
+	r.syncedInputsLock.Lock()
+	defer r.syncedInputsLock.Unlock()
+	if len(inputs) == 0 {
+		delete(r.lastSyncedInputs, key)
+		return
+	}
+	if r.lastSyncedInputs == nil {
+		r.lastSyncedInputs = make(map[types.NamespacedName]string)
+	}
+	r.lastSyncedInputs[key] = inputs
+}
+
+// forgetSecretSync drops the state kept for a SecretSync which is deleted or
+// handled by another controller.
+func (r *SecretSyncReconciler) forgetSecretSync(key types.NamespacedName) {
+	r.statsReporter.forgetSecretSync(key)
+	r.setSyncedInputs(key, "")
+	r.setAppliedSecret(key, nil)
+	r.Scheduler.forget(key)
+	r.retryLimiter.Forget(reconcile.Request{NamespacedName: key})
+}
+
+// isManagedByController returns true if the SecretSync should be synchronized
+// by this controller instance.
+func (r *SecretSyncReconciler) isManagedByController(ss *secretsyncv1alpha1.SecretSync) bool {
+	return ss.Spec.SecretSyncControllerName == r.ControllerName
+}
+
+// isManagedObject is the client.Object variant of isManagedByController used
+// by the event predicates.
+func (r *SecretSyncReconciler) isManagedObject(obj client.Object) bool {
+	ss, ok := obj.(*secretsyncv1alpha1.SecretSync)
+	if !ok {
+		return false
+	}
+	return r.isManagedByController(ss)
+}
+
 // processIfSecretChanged checks if the secret sync object has changed.
 func (r *SecretSyncReconciler) processIfSecretChanged(oldObj, newObj client.Object) bool {
 	ssOldObj := oldObj.(*secretsyncv1alpha1.SecretSync)
@@ -412,37 +840,105 @@ func (r *SecretSyncReconciler) processIfSecretChanged(oldObj, newObj client.Obje
 // we don't need to trigger the reconcile function when the status of the secret sync object is updated.
 func (r *SecretSyncReconciler) shouldReconcilePredicate() predicate.Funcs {
 	return predicate.Funcs{
-		CreateFunc: func(_ event.CreateEvent) bool {
-			return true
+		CreateFunc: func(e event.CreateEvent) bool {
+			return r.isManagedObject(e.Object)
 		},
 		UpdateFunc: func(e event.UpdateEvent) bool {
+			if !r.isManagedObject(e.ObjectNew) {
+				return false
+			}
+			// the deletion policy is applied once the object is marked for deletion
+			if e.ObjectOld.GetDeletionTimestamp().IsZero() && !e.ObjectNew.GetDeletionTimestamp().IsZero() {
+				return true
+			}
 			return r.processIfSecretChanged(e.ObjectOld, e.ObjectNew)
 		},
 		DeleteFunc: func(_ event.DeleteEvent) bool {
 			return false
 		},
-		GenericFunc: func(_ event.GenericEvent) bool {
-			return true
+		GenericFunc: func(e event.GenericEvent) bool {
+			return r.isManagedObject(e.Object)
 		},
 	}
 }
 
+// secretProviderClassNameIndexer indexes SecretSyncs by the name of the
+// SecretProviderClass they reference.
+func secretProviderClassNameIndexer(obj client.Object) []string {
+	ss, ok := obj.(*secretsyncv1alpha1.SecretSync)
+	if !ok || len(ss.Spec.SecretProviderClassName) == 0 {
+		return nil
+	}
+	return []string{ss.Spec.SecretProviderClassName}
+}
+
+// secretSyncsForSecretProviderClass maps a SecretProviderClass event to reconcile
+// requests for every SecretSync in the same namespace that references it.
+func (r *SecretSyncReconciler) secretSyncsForSecretProviderClass(ctx context.Context, obj client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	ssList := &secretsyncv1alpha1.SecretSyncList{}
+	if err := r.List(ctx, ssList,
+		client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{secretProviderClassNameIndexKey: obj.GetName()},
+	); err != nil {
+		logger.Error(err, "failed to list SecretSyncs for SecretProviderClass", "namespace", obj.GetNamespace(), "name", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(ssList.Items))
+	for idx := range ssList.Items {
+		if !r.isManagedByController(&ssList.Items[idx]) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&ssList.Items[idx]),
+		})
+	}
+
+	logger.V(4).Info("SecretProviderClass changed, enqueuing SecretSyncs", "namespace", obj.GetNamespace(), "name", obj.GetName(), "count", len(requests))
+	return requests
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *SecretSyncReconciler) SetupWithManager(mgr ctrl.Manager, secretsPollingInterval time.Duration) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &secretsyncv1alpha1.SecretSync{}, secretProviderClassNameIndexKey, secretProviderClassNameIndexer); err != nil {
+		return err
+	}
+
+	statsReporter, err := newStatsReporter(otel.Meter(scope))
+	if err != nil {
+		return err
+	}
+	r.statsReporter = statsReporter
+	statsReporter.rotationQueueDepth = r.Scheduler.depth
+	// every SecretSync is requeued at its next sync time by Reconcile
+	r.rotationPollInterval = secretsPollingInterval
+	r.retryLimiter = newRetryRateLimiter(r.RetryBackoff)
+
 	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
-		For(&secretsyncv1alpha1.SecretSync{}).
-		WithEventFilter(r.shouldReconcilePredicate())
+		WithOptions(controller.Options{RateLimiter: r.retryLimiter}).
+		For(&secretsyncv1alpha1.SecretSync{}, builder.WithPredicates(r.shouldReconcilePredicate())).
+		Watches(
+			&secretsstorecsiv1.SecretProviderClass{},
+			handler.EnqueueRequestsFromMapFunc(r.secretSyncsForSecretProviderClass),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		WatchesMetadata(
+			&corev1.Secret{},
+			managedSecretHandler(mgr.GetScheme(), mgr.GetRESTMapper()),
+			builder.WithPredicates(managedSecretPredicate()),
+		)
 
-	if secretsPollingInterval > 0 {
-		periodicChannel, pollingFunc := r.providerPollingFunc(secretsPollingInterval, mgr.GetCache())
+	if notifier, ok := r.ProviderClients.(ProvidersNotifier); ok {
+		providersChannel, discoveryFunc := r.providerDiscoveryFunc(notifier, mgr.GetCache())
 
-		if err := mgr.Add(pollingFunc); err != nil {
+		if err := mgr.Add(discoveryFunc); err != nil {
 			return err
 		}
-		// events from WatchesRawSource don't go through predicates so this will always cause syncs to trigger
 		controllerBuilder.WatchesRawSource(
 			source.TypedChannel(
-				periodicChannel,
+				providersChannel,
 				&handler.TypedEnqueueRequestForObject[*secretsyncv1alpha1.SecretSync]{},
 			),
 		)
@@ -451,43 +947,66 @@ func (r *SecretSyncReconciler) SetupWithManager(mgr ctrl.Manager, secretsPolling
 	return controllerBuilder.Complete(r)
 }
 
-func (r *SecretSyncReconciler) providerPollingFunc(pollInterval time.Duration, informersCache cache.Cache) (chan event.TypedGenericEvent[*secretsyncv1alpha1.SecretSync], manager.RunnableFunc) {
-	periodicChannel := make(chan event.TypedGenericEvent[*secretsyncv1alpha1.SecretSync], 1024)
+// providerDiscoveryFunc requeues the SecretSyncs which failed because their
+// provider was not found every time new providers are discovered.
+func (r *SecretSyncReconciler) providerDiscoveryFunc(notifier ProvidersNotifier, informersCache cache.Cache) (chan event.TypedGenericEvent[*secretsyncv1alpha1.SecretSync], manager.RunnableFunc) {
+	providersChannel := make(chan event.TypedGenericEvent[*secretsyncv1alpha1.SecretSync], 1024)
+
+	// the discoveries are coalesced, every SecretSync waiting for a provider
+	// is requeued anyway
+	discovered := make(chan struct{}, 1)
+	notifier.AddProvidersListener(func(_, added []string) {
+		if len(added) == 0 {
+			return
+		}
+		select {
+		case discovered <- struct{}{}:
+		default:
+		}
+	})
 
-	return periodicChannel, func(ctx context.Context) error {
-		defer close(periodicChannel)
+	return providersChannel, func(ctx context.Context) error {
+		defer close(providersChannel)
 
 		if ok := informersCache.WaitForCacheSync(ctx); !ok {
 			return fmt.Errorf("timed out waiting for cache sync")
 		}
 
-		ticker := time.NewTicker(pollInterval)
-		defer ticker.Stop()
-
 		logger := log.FromContext(ctx)
 		for {
 			select {
-			case <-ticker.C:
+			case <-discovered:
 				ssList := &secretsyncv1alpha1.SecretSyncList{}
 				if err := r.List(ctx, ssList); err != nil {
 					logger.Error(err, "failed to list SecretSyncs")
 					continue
 				}
 				for idx := range ssList.Items {
+					ss := &ssList.Items[idx]
+					if !r.isManagedByController(ss) || !waitsForProvider(ss) {
+						continue
+					}
+					logger.V(4).Info("provider discovered, requeueing SecretSync", "namespace", ss.Namespace, "name", ss.Name)
 					select {
-					case periodicChannel <- event.TypedGenericEvent[*secretsyncv1alpha1.SecretSync]{Object: ssList.Items[idx].DeepCopy()}:
+					case providersChannel <- event.TypedGenericEvent[*secretsyncv1alpha1.SecretSync]{Object: ss.DeepCopy()}:
 					case <-ctx.Done():
-						goto handle_context_done
+						return nil
 					}
 				}
-
 			case <-ctx.Done():
-				goto handle_context_done
+				return nil
 			}
 		}
+	}
+}
 
-	handle_context_done:
-		logger.Info("shutting down periodic resync")
-		return nil
+// waitsForProvider returns true if the last sync of the SecretSync failed
+// because its provider was not found.
+func waitsForProvider(ss *secretsyncv1alpha1.SecretSync) bool {
+	for _, conditionType := range []string{ConditionTypeCreate, ConditionTypeUpdate} {
+		if condition := meta.FindStatusCondition(ss.Status.Conditions, conditionType); condition != nil && condition.Reason == ConditionReasonProviderNotFound {
+			return true
+		}
 	}
+	return false
 }
@@ -17,23 +17,47 @@ limitations under the License.
 package controller
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"maps"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	fakeclient "k8s.io/client-go/kubernetes/fake"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	clocktesting "k8s.io/utils/clock/testing"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	secretsstorecsiv1 "sigs.k8s.io/secrets-store-csi-driver/apis/v1"
 	providerfake "sigs.k8s.io/secrets-store-csi-driver/provider/fake"
 	"sigs.k8s.io/secrets-store-csi-driver/provider/v1alpha1"
@@ -138,7 +162,6 @@ func TestReconcile(t *testing.T) {
 					"foo": []byte("bar"),
 				},
 			},
-			expectedErrorString: `secretsyncs.secret-sync.x-k8s.io "sse2esecret" not found`,
 		},
 		{
 			name: "use of reserved label returns validation error",
@@ -185,7 +208,7 @@ func TestReconcile(t *testing.T) {
 					"foo": []byte("bar"),
 				},
 			},
-			expectedErrorString: "label secrets-store.sync.x-k8s.io is reserved for use by the Secrets Store Sync Controller",
+			expectedErrorString: "terminal error: label secrets-store.sync.x-k8s.io is reserved for use by the Secrets Store Sync Controller",
 			expectedConditions: []metav1.Condition{
 				{
 					Type:    "SecretCreated",
@@ -245,7 +268,7 @@ func TestReconcile(t *testing.T) {
 					"foo": []byte("bar"),
 				},
 			},
-			expectedErrorString: "annotation secrets-store.sync.x-k8s.io is reserved for use by the Secrets Store Sync Controller",
+			expectedErrorString: "terminal error: annotation secrets-store.sync.x-k8s.io is reserved for use by the Secrets Store Sync Controller",
 			expectedConditions: []metav1.Condition{
 				{
 					Type:    "SecretCreated",
@@ -291,13 +314,13 @@ func TestReconcile(t *testing.T) {
 					"foo": []byte("bar"),
 				},
 			},
-			expectedErrorString: `secretproviderclasses.secrets-store.csi.x-k8s.io "test-spc" not found`,
+			expectedErrorString: `terminal error: SecretProviderClass "test-spc" does not exist in namespace "default"`,
 			expectedConditions: []metav1.Condition{
 				{
 					Type:    "SecretCreated",
 					Status:  metav1.ConditionFalse,
 					Reason:  "SecretProviderClassMisconfigured",
-					Message: `failed to get SecretProviderClass "test-spc": secretproviderclasses.secrets-store.csi.x-k8s.io "test-spc" not found`,
+					Message: `SecretProviderClass "test-spc" does not exist in namespace "default"`,
 				},
 				{
 					Type:   "SecretUpdated",
@@ -348,12 +371,12 @@ func TestReconcile(t *testing.T) {
 					"foo": []byte("bar"),
 				},
 			},
-			expectedErrorString: `provider not found: provider "invalid-fake-provider"`,
+			expectedErrorString: `terminal error: provider not found: provider "invalid-fake-provider"`,
 			expectedConditions: []metav1.Condition{
 				{
 					Type:    "SecretCreated",
 					Status:  metav1.ConditionFalse,
-					Reason:  "SecretProviderClassMisconfigured",
+					Reason:  ConditionReasonProviderNotFound,
 					Message: `fetching secrets from the provider failed: provider not found: provider "invalid-fake-provider"`,
 				},
 				{
@@ -420,6 +443,59 @@ func TestReconcile(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "secret template fails to render",
+			secretProviderClassToProcess: &secretsstorecsiv1.SecretProviderClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-spc",
+					Namespace: "default",
+				},
+				Spec: secretsstorecsiv1.SecretProviderClassSpec{
+					Provider: "fake-provider",
+					Parameters: map[string]string{
+						"foo": "v1",
+					},
+				},
+			},
+			secretSyncToProcess: &secretsyncv1alpha1.SecretSync{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "sse2esecret",
+					Namespace: "default",
+				},
+				Spec: secretsyncv1alpha1.SecretSyncSpec{
+					ServiceAccountName:      "default",
+					SecretProviderClassName: "test-spc",
+					SecretObject: secretsyncv1alpha1.SecretObject{
+						Type: "Opaque",
+						Template: &secretsyncv1alpha1.SecretObjectTemplate{
+							Data: map[string]string{
+								"bar": "{{ .missing }}",
+							},
+						},
+					},
+				},
+			},
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "sse2esecret",
+					Namespace: "default",
+				},
+			},
+			expectedErrorString: `failed to render template for key bar: template: bar:1:3: executing "bar" at <.missing>: map has no entry for key "missing"`,
+			expectedConditions: []metav1.Condition{
+				{
+					Type:    "SecretCreated",
+					Status:  metav1.ConditionFalse,
+					Reason:  ConditionReasonSecretTemplateError,
+					Message: `fetching secrets from the provider failed: failed to render template for key bar: template: bar:1:3: executing "bar" at <.missing>: map has no entry for key "missing"`,
+				},
+				{
+					Type:   "SecretUpdated",
+					Status: metav1.ConditionUnknown,
+					Reason: "NoUpdatesAttemptedYet",
+				},
+			},
+		},
 	}
 
 	scheme := setupScheme(t)
@@ -546,6 +622,7 @@ func TestConditionsOnHashChange(t *testing.T) {
 			Contents: []byte("bar"),
 		},
 	})
+	testSecretSyncReconciler.fakeProviderServer.SetObjects(map[string]string{"secret/object1": "v2"})
 
 	// Sleep so that we can observe LastTransitionTime change in LastSuccessfulSyncTime
 	time.Sleep(1 * time.Second)
@@ -582,76 +659,2413 @@ func TestConditionsOnHashChange(t *testing.T) {
 	}
 }
 
-func getSecretSyncObject(t *testing.T, ssc *SecretSyncReconciler, req ctrl.Request) *secretsyncv1alpha1.SecretSync {
-	t.Helper()
+func TestReconcileIgnoresOtherControllers(t *testing.T) {
+	secretProviderClassToProcess := &secretsstorecsiv1.SecretProviderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-spc",
+			Namespace: "default",
+		},
+		Spec: secretsstorecsiv1.SecretProviderClassSpec{
+			Provider: "fake-provider",
+			Parameters: map[string]string{
+				"foo": "v1",
+			},
+		},
+	}
+	secretSyncToProcess := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			SecretSyncControllerName: "other-controller",
+			ServiceAccountName:       "default",
+			SecretProviderClassName:  "test-spc",
+			SecretObject: secretsyncv1alpha1.SecretObject{
+				Type: "Opaque",
+				Data: []secretsyncv1alpha1.SecretObjectData{
+					{
+						SourcePath: "foo",
+						TargetKey:  "bar",
+					},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "unrelated",
+			Namespace: "default",
+		},
+	}
 
-	secretSync := &secretsyncv1alpha1.SecretSync{}
-	err := ssc.Get(context.Background(), req.NamespacedName, secretSync)
-	if err != nil && !apierrors.IsNotFound(err) {
-		t.Fatalf("error getting secret sync: %v", err)
+	scheme := setupScheme(t)
+	testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, secretProviderClassToProcess, secretSyncToProcess, secret)
+
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
 	}
 
-	return secretSync
+	if _, err := testSecretSyncReconciler.secretSyncReconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ss := getSecretSyncObject(t, testSecretSyncReconciler.secretSyncReconciler, req)
+	if len(ss.Status.Conditions) != 0 || len(ss.Status.SyncHash) != 0 {
+		t.Fatalf("expected SecretSync of another controller to be left untouched, got status %+v", ss.Status)
+	}
+
+	_, err := testSecretSyncReconciler.secretSyncReconciler.Clientset.CoreV1().Secrets("default").Get(context.Background(), "sse2esecret", metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("expected secret to not be created, got err %v", err)
+	}
+
+	// the matching controller instance syncs the object
+	testSecretSyncReconciler.secretSyncReconciler.ControllerName = "other-controller"
+	if _, err := testSecretSyncReconciler.secretSyncReconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ss = getSecretSyncObject(t, testSecretSyncReconciler.secretSyncReconciler, req)
+	if len(ss.Status.SyncHash) == 0 {
+		t.Fatal("expected SecretSync to be synced by the matching controller")
+	}
 }
 
-func newSecretSyncReconciler(
-	t *testing.T,
-	scheme *runtime.Scheme,
-	spc *secretsstorecsiv1.SecretProviderClass,
-	secretSync *secretsyncv1alpha1.SecretSync,
-	testSecret *corev1.Secret,
-) *testSecretSyncReconciler {
-	t.Helper()
+func TestShouldReconcilePredicate(t *testing.T) {
+	newSecretSync := func(controllerName string, generation int64) *secretsyncv1alpha1.SecretSync {
+		return &secretsyncv1alpha1.SecretSync{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "sse2esecret",
+				Namespace:  "default",
+				Generation: generation,
+			},
+			Spec: secretsyncv1alpha1.SecretSyncSpec{
+				SecretSyncControllerName: controllerName,
+			},
+		}
+	}
 
-	initObjects := []client.Object{
-		testSecret,
-		spc,
-		secretSync,
+	r := &SecretSyncReconciler{ControllerName: "vault"}
+	p := r.shouldReconcilePredicate()
+
+	tests := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{
+			name: "create for this controller",
+			got:  p.Create(event.CreateEvent{Object: newSecretSync("vault", 1)}),
+			want: true,
+		},
+		{
+			name: "create for another controller",
+			got:  p.Create(event.CreateEvent{Object: newSecretSync("", 1)}),
+			want: false,
+		},
+		{
+			name: "spec update for this controller",
+			got:  p.Update(event.UpdateEvent{ObjectOld: newSecretSync("vault", 1), ObjectNew: newSecretSync("vault", 2)}),
+			want: true,
+		},
+		{
+			name: "status update for this controller",
+			got:  p.Update(event.UpdateEvent{ObjectOld: newSecretSync("vault", 1), ObjectNew: newSecretSync("vault", 1)}),
+			want: false,
+		},
+		{
+			name: "object moved to another controller",
+			got:  p.Update(event.UpdateEvent{ObjectOld: newSecretSync("vault", 1), ObjectNew: newSecretSync("aws", 2)}),
+			want: false,
+		},
+		{
+			name: "object moved to this controller",
+			got:  p.Update(event.UpdateEvent{ObjectOld: newSecretSync("aws", 1), ObjectNew: newSecretSync("vault", 2)}),
+			want: true,
+		},
+		{
+			name: "generic for another controller",
+			got:  p.Generic(event.GenericEvent{Object: newSecretSync("aws", 1)}),
+			want: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.got != test.want {
+				t.Errorf("expected %t, got %t", test.want, test.got)
+			}
+		})
 	}
+}
 
-	// Create a fake client to mock API calls
-	ctrlClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).WithStatusSubresource(secretSync).Build()
+func TestSecretSyncsForSecretProviderClass(t *testing.T) {
+	newSecretSync := func(name, namespace, spcName, controllerName string) *secretsyncv1alpha1.SecretSync {
+		return &secretsyncv1alpha1.SecretSync{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: secretsyncv1alpha1.SecretSyncSpec{
+				SecretSyncControllerName: controllerName,
+				SecretProviderClassName:  spcName,
+			},
+		}
+	}
 
-	// Create a mock provider named "fake-provider".
-	// t.TempDir() creates a temporary directory which might have long path. sever.Start() fails with long path.
-	// So, create a temporary directory with shorter path.
-	socketPath, _ := os.MkdirTemp("/tmp", "e2e-secret-sync-controller-test-")
-	t.Cleanup(func() {
-		err := os.RemoveAll(socketPath)
+	scheme := setupScheme(t)
+	ctrlClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			newSecretSync("ss1", "default", "test-spc", ""),
+			newSecretSync("ss2", "default", "test-spc", ""),
+			newSecretSync("ss3", "default", "other-spc", ""),
+			newSecretSync("ss4", "other-ns", "test-spc", ""),
+			newSecretSync("ss5", "default", "test-spc", "other-controller"),
+		).
+		WithIndex(&secretsyncv1alpha1.SecretSync{}, secretProviderClassNameIndexKey, secretProviderClassNameIndexer).
+		Build()
+
+	r := &SecretSyncReconciler{Client: ctrlClient}
+	spc := &secretsstorecsiv1.SecretProviderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-spc",
+			Namespace: "default",
+		},
+	}
+
+	got := r.secretSyncsForSecretProviderClass(context.Background(), spc)
+	want := []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: "default", Name: "ss1"}},
+		{NamespacedName: types.NamespacedName{Namespace: "default", Name: "ss2"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected requests %v, got %v", want, got)
+	}
+}
+
+func TestReconcileSecretDrift(t *testing.T) {
+	secretProviderClassToProcess := &secretsstorecsiv1.SecretProviderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-spc",
+			Namespace: "default",
+		},
+		Spec: secretsstorecsiv1.SecretProviderClassSpec{
+			Provider: "fake-provider",
+			Parameters: map[string]string{
+				"foo": "v1",
+			},
+		},
+	}
+	secretSyncToProcess := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			ServiceAccountName:      "default",
+			SecretProviderClassName: "test-spc",
+			SecretObject: secretsyncv1alpha1.SecretObject{
+				Type: "Opaque",
+				Data: []secretsyncv1alpha1.SecretObjectData{
+					{
+						SourcePath: "foo",
+						TargetKey:  "bar",
+					},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+			Labels: map[string]string{
+				controllerLabelKey: "",
+			},
+		},
+	}
+
+	scheme := setupScheme(t)
+	testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, secretProviderClassToProcess, secretSyncToProcess, secret)
+	ssc := testSecretSyncReconciler.secretSyncReconciler
+	recorder := ssc.EventRecorder.(*record.FakeRecorder)
+
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+	}
+
+	reconcileAndExpectDrift := func(expected string) {
+		t.Helper()
+
+		if _, err := ssc.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		events := drainEvents(recorder)
+		drifted := slices.ContainsFunc(events, func(e string) bool {
+			return strings.Contains(e, EventReasonSecretDrifted)
+		})
+		switch {
+		case len(expected) == 0 && drifted:
+			t.Fatalf("unexpected SecretDrifted event, got %v", events)
+		case len(expected) > 0 && !slices.ContainsFunc(events, func(e string) bool {
+			return strings.Contains(e, EventReasonSecretDrifted) && strings.Contains(e, expected)
+		}):
+			t.Fatalf("expected a SecretDrifted event for a %s secret, got %v", expected, events)
+		}
+
+		restored, err := ssc.Clientset.CoreV1().Secrets("default").Get(context.Background(), "sse2esecret", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("expected secret to be restored, got error: %v", err)
+		}
+		if got := string(restored.Data["bar"]); got != "foo" {
+			t.Fatalf("expected restored secret data %q, got %q", "foo", got)
+		}
+		if !hasControllerLabel(restored) {
+			t.Fatalf("expected restored secret to have the controller label, got %v", restored.Labels)
+		}
+	}
+	updateSecret := func(update func(secret *corev1.Secret)) {
+		t.Helper()
+
+		secret, err := ssc.Clientset.CoreV1().Secrets("default").Get(context.Background(), "sse2esecret", metav1.GetOptions{})
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-	})
+		update(secret)
+		secret.ManagedFields = nil
+		if _, err := ssc.Clientset.CoreV1().Secrets("default").Update(context.Background(), secret, metav1.UpdateOptions{FieldManager: "kubectl-edit"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
 
-	server, err := providerfake.NewMocKCSIProviderServer(filepath.Join(socketPath, "fake-provider.sock"))
-	if err != nil {
-		t.Fatalf("unexpected mock provider failure: %v", err)
+	// create, then an untouched secret
+	reconcileAndExpectDrift("")
+	reconcileAndExpectDrift("")
+
+	// delete the secret behind the controller's back
+	if err := ssc.Clientset.CoreV1().Secrets("default").Delete(context.Background(), "sse2esecret", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
+	reconcileAndExpectDrift(driftTypeDeleted)
 
-	server.SetObjects(map[string]string{"secret/object1": "v1"})
-	server.SetFiles([]*v1alpha1.File{
-		{
-			Path:     "foo",
-			Mode:     0644,
-			Contents: []byte("foo"),
-		},
+	// modify a key applied by the controller
+	updateSecret(func(secret *corev1.Secret) {
+		secret.Data["bar"] = []byte("modified")
 	})
+	reconcileAndExpectDrift(driftTypeModified)
+	reconcileAndExpectDrift("")
 
-	if err := server.Start(); err != nil {
-		t.Fatalf("unexpected mock provider start failure: %v", err)
-	}
-	t.Cleanup(server.Stop)
+	// remove the controller label
+	updateSecret(func(secret *corev1.Secret) {
+		delete(secret.Labels, controllerLabelKey)
+	})
+	reconcileAndExpectDrift(driftTypeModified)
 
-	providerClients := provider.NewPluginClientBuilder([]string{socketPath})
+	// a key only set by another field manager is left alone
+	updateSecret(func(secret *corev1.Secret) {
+		secret.Data["extra"] = []byte("value")
+	})
+	reconcileAndExpectDrift("")
+	reconcileAndExpectDrift("")
+}
 
-	// Create a ReconcileSecretSync object with the scheme and fake client
-	kubeClient := fakeclient.NewClientset(testSecret)
+func TestManagedSecretWatch(t *testing.T) {
+	secretProviderClassToProcess := &secretsstorecsiv1.SecretProviderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-spc",
+			Namespace: "default",
+		},
+		Spec: secretsstorecsiv1.SecretProviderClassSpec{
+			Provider: "fake-provider",
+			Parameters: map[string]string{
+				"foo": "v1",
+			},
+		},
+	}
+	secretSyncToProcess := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			ServiceAccountName:      "default",
+			SecretProviderClassName: "test-spc",
+			SecretObject: secretsyncv1alpha1.SecretObject{
+				Type: "Opaque",
+				Data: []secretsyncv1alpha1.SecretObjectData{
+					{
+						SourcePath: "foo",
+						TargetKey:  "bar",
+					},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+			Labels: map[string]string{
+				controllerLabelKey: "",
+			},
+		},
+	}
+
+	scheme := setupScheme(t)
+	testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, secretProviderClassToProcess, secretSyncToProcess, secret)
+	ssc := testSecretSyncReconciler.secretSyncReconciler
+	recorder := ssc.EventRecorder.(*record.FakeRecorder)
+	// the secret is only synced again before the next rotation when it drifted
+	ssc.rotationPollInterval = 12 * time.Hour
+
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+	}
+	if _, err := ssc.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	drainEvents(recorder)
+
+	applied, err := ssc.Clientset.CoreV1().Secrets("default").Get(context.Background(), "sse2esecret", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secretMeta := &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: applied.ObjectMeta,
+	}
+
+	// the changes made by the controller are not requeued
+	predicate := managedSecretPredicate()
+	if predicate.Create(event.CreateEvent{Object: secretMeta}) {
+		t.Fatalf("expected the creation of the secret to be filtered")
+	}
+	changed := secretMeta.DeepCopy()
+	changed.ManagedFields = append(changed.ManagedFields, metav1.ManagedFieldsEntry{
+		Manager:   "kubectl-edit",
+		Operation: metav1.ManagedFieldsOperationUpdate,
+	})
+	if !predicate.Update(event.UpdateEvent{ObjectOld: secretMeta, ObjectNew: changed}) {
+		t.Fatalf("expected a change by another field manager to be requeued")
+	}
+	reapplied := secretMeta.DeepCopy()
+	reapplied.ManagedFields = []metav1.ManagedFieldsEntry{{
+		Manager:   secretSyncControllerFieldManager,
+		Operation: metav1.ManagedFieldsOperationApply,
+		Time:      &metav1.Time{Time: time.Now().Add(time.Hour)},
+	}}
+	if predicate.Update(event.UpdateEvent{ObjectOld: secretMeta, ObjectNew: reapplied}) {
+		t.Fatalf("expected a change by the controller to be filtered")
+	}
+
+	// delete the secret behind the controller's back, the SecretSync owning it
+	// is enqueued
+	if err := ssc.Clientset.CoreV1().Secrets("default").Delete(context.Background(), "sse2esecret", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deleted := event.DeleteEvent{Object: secretMeta}
+	if !predicate.Delete(deleted) {
+		t.Fatalf("expected the deletion of the secret to be requeued")
+	}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{secretsyncv1alpha1.GroupVersion})
+	mapper.Add(secretsyncv1alpha1.GroupVersion.WithKind("SecretSync"), meta.RESTScopeNamespace)
+	queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	defer queue.ShutDown()
+	managedSecretHandler(scheme, mapper).Delete(context.Background(), deleted, queue)
+	if queue.Len() != 1 {
+		t.Fatalf("expected the SecretSync to be enqueued, got %d requests", queue.Len())
+	}
+	enqueued, _ := queue.Get()
+	if enqueued != req {
+		t.Fatalf("expected %v to be enqueued, got %v", req, enqueued)
+	}
+
+	// the secret is recreated before the next rotation
+	if _, err := ssc.Reconcile(context.Background(), enqueued); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recreated, err := ssc.Clientset.CoreV1().Secrets("default").Get(context.Background(), "sse2esecret", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the secret to be recreated, got error: %v", err)
+	}
+	if got := string(recreated.Data["bar"]); got != "foo" {
+		t.Fatalf("expected recreated secret data %q, got %q", "foo", got)
+	}
+	if events := drainEvents(recorder); !slices.ContainsFunc(events, func(e string) bool {
+		return strings.Contains(e, EventReasonSecretDrifted) && strings.Contains(e, driftTypeDeleted)
+	}) {
+		t.Fatalf("expected a SecretDrifted event for a deleted secret, got %v", events)
+	}
+}
+
+func TestReconcileAddsFinalizer(t *testing.T) {
+	secretProviderClassToProcess := &secretsstorecsiv1.SecretProviderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-spc",
+			Namespace: "default",
+		},
+		Spec: secretsstorecsiv1.SecretProviderClassSpec{
+			Provider: "fake-provider",
+			Parameters: map[string]string{
+				"foo": "v1",
+			},
+		},
+	}
+	secretSyncToProcess := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			ServiceAccountName:      "default",
+			SecretProviderClassName: "test-spc",
+			SecretObject: secretsyncv1alpha1.SecretObject{
+				Type: "Opaque",
+				Data: []secretsyncv1alpha1.SecretObjectData{
+					{
+						SourcePath: "foo",
+						TargetKey:  "bar",
+					},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "unrelated",
+			Namespace: "default",
+		},
+	}
+
+	scheme := setupScheme(t)
+	testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, secretProviderClassToProcess, secretSyncToProcess, secret)
+	ssc := testSecretSyncReconciler.secretSyncReconciler
+
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+	}
+
+	if _, err := ssc.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ss := getSecretSyncObject(t, ssc, req)
+	if !slices.Contains(ss.Finalizers, secretSyncFinalizer) {
+		t.Fatalf("expected finalizer %q, got %v", secretSyncFinalizer, ss.Finalizers)
+	}
+
+	created, err := ssc.Clientset.CoreV1().Secrets("default").Get(context.Background(), "sse2esecret", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(created.OwnerReferences) != 1 {
+		t.Fatalf("expected one owner reference, got %v", created.OwnerReferences)
+	}
+	if ref := created.OwnerReferences[0]; ref.Controller == nil || !*ref.Controller || ref.BlockOwnerDeletion == nil || !*ref.BlockOwnerDeletion {
+		t.Fatalf("expected a controller owner reference blocking owner deletion, got %+v", ref)
+	}
+}
+
+func TestReconcileDeletionPolicy(t *testing.T) {
+	tests := []struct {
+		name           string
+		deletionPolicy secretsyncv1alpha1.DeletionPolicy
+		foreground     bool
+		expectOwnerRef bool
+		expectLabel    bool
+	}{
+		{
+			name:           "delete leaves the secret to the garbage collector",
+			deletionPolicy: secretsyncv1alpha1.DeletionPolicyDelete,
+			expectOwnerRef: true,
+			expectLabel:    true,
+		},
+		{
+			name:           "default policy deletes",
+			expectOwnerRef: true,
+			expectLabel:    true,
+		},
+		{
+			name:           "retain releases the secret",
+			deletionPolicy: secretsyncv1alpha1.DeletionPolicyRetain,
+			expectOwnerRef: false,
+			expectLabel:    false,
+		},
+		{
+			name:           "orphan keeps the controller label",
+			deletionPolicy: secretsyncv1alpha1.DeletionPolicyOrphan,
+			expectOwnerRef: false,
+			expectLabel:    true,
+		},
+		{
+			name:           "retain releases the secret in a foreground deletion",
+			deletionPolicy: secretsyncv1alpha1.DeletionPolicyRetain,
+			foreground:     true,
+			expectOwnerRef: false,
+			expectLabel:    false,
+		},
+		{
+			name:           "orphan releases the secret in a foreground deletion",
+			deletionPolicy: secretsyncv1alpha1.DeletionPolicyOrphan,
+			foreground:     true,
+			expectOwnerRef: false,
+			expectLabel:    true,
+		},
+	}
+
+	scheme := setupScheme(t)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spc := &secretsstorecsiv1.SecretProviderClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-spc",
+					Namespace: "default",
+				},
+			}
+			ss := &secretsyncv1alpha1.SecretSync{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "sse2esecret",
+					Namespace:         "default",
+					UID:               "ss-uid",
+					DeletionTimestamp: &metav1.Time{Time: time.Now()},
+					Finalizers:        []string{secretSyncFinalizer},
+				},
+				Spec: secretsyncv1alpha1.SecretSyncSpec{
+					ServiceAccountName:      "default",
+					SecretProviderClassName: "test-spc",
+					DeletionPolicy:          test.deletionPolicy,
+				},
+			}
+			if test.foreground {
+				// set by the API server, removed by the garbage collector once
+				// the secret does not block the deletion anymore
+				ss.Finalizers = append(ss.Finalizers, metav1.FinalizerDeleteDependents)
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "sse2esecret",
+					Namespace: "default",
+					Labels: map[string]string{
+						controllerLabelKey: "",
+						"app":              "test",
+					},
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion: "secret-sync.x-k8s.io/v1alpha1",
+							Kind:       "SecretSync",
+							Name:       "sse2esecret",
+							UID:        "ss-uid",
+						},
+					},
+				},
+			}
+
+			testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, spc, ss, secret)
+			ssc := testSecretSyncReconciler.secretSyncReconciler
+
+			req := ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "sse2esecret",
+					Namespace: "default",
+				},
+			}
+
+			if _, err := ssc.Reconcile(context.Background(), req); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// the SecretSync is gone once the finalizer is removed
+			deleted := &secretsyncv1alpha1.SecretSync{}
+			err := ssc.Get(context.Background(), req.NamespacedName, deleted)
+			switch {
+			case !test.foreground && !apierrors.IsNotFound(err):
+				t.Fatalf("expected SecretSync to be deleted, got %v", err)
+			case test.foreground && (err != nil || !slices.Equal(deleted.Finalizers, []string{metav1.FinalizerDeleteDependents})):
+				t.Fatalf("expected only the foreground deletion finalizer to be left, got %v, %v", deleted.Finalizers, err)
+			}
+
+			got, err := ssc.Clientset.CoreV1().Secrets("default").Get(context.Background(), "sse2esecret", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if hasOwnerRef := len(got.OwnerReferences) > 0; hasOwnerRef != test.expectOwnerRef {
+				t.Errorf("expected owner reference present: %t, got %v", test.expectOwnerRef, got.OwnerReferences)
+			}
+			if _, hasLabel := got.Labels[controllerLabelKey]; hasLabel != test.expectLabel {
+				t.Errorf("expected controller label present: %t, got %v", test.expectLabel, got.Labels)
+			}
+			if got.Labels["app"] != "test" {
+				t.Errorf("expected user labels to be kept, got %v", got.Labels)
+			}
+
+			// reconciling a deleted SecretSync is a no-op
+			if _, err := ssc.Reconcile(context.Background(), req); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestBlocksOwnerDeletion(t *testing.T) {
+	tests := []struct {
+		deletionPolicy secretsyncv1alpha1.DeletionPolicy
+		expected       bool
+	}{
+		{deletionPolicy: "", expected: true},
+		{deletionPolicy: secretsyncv1alpha1.DeletionPolicyDelete, expected: true},
+		{deletionPolicy: secretsyncv1alpha1.DeletionPolicyRetain, expected: false},
+		{deletionPolicy: secretsyncv1alpha1.DeletionPolicyOrphan, expected: false},
+	}
+
+	for _, test := range tests {
+		ss := &secretsyncv1alpha1.SecretSync{Spec: secretsyncv1alpha1.SecretSyncSpec{DeletionPolicy: test.deletionPolicy}}
+		if got := blocksOwnerDeletion(ss); got != test.expected {
+			t.Errorf("expected the owner deletion blocked with the %q policy: %t, got %t", test.deletionPolicy, test.expected, got)
+		}
+	}
+}
+
+func TestReconcileSecretName(t *testing.T) {
+	tests := []struct {
+		name               string
+		secretObjectName   string
+		syncedSecretName   string
+		existingSecret     string
+		existingSecretUID  types.UID
+		orphaned           bool
+		createdMeanwhile   bool
+		expectedError      bool
+		expectedReason     string
+		expectedSecretName string
+		expectedDeleted    string
+	}{
+		{
+			name:               "custom secret name",
+			secretObjectName:   "custom-secret",
+			existingSecret:     "unrelated",
+			expectedReason:     ConditionReasonCreateSuccessful,
+			expectedSecretName: "custom-secret",
+		},
+		{
+			name:              "secret managed by another SecretSync",
+			secretObjectName:  "shared-secret",
+			existingSecret:    "shared-secret",
+			existingSecretUID: "other-uid",
+			expectedError:     true,
+			expectedReason:    ConditionReasonSecretNameConflict,
+		},
+		{
+			name:             "secret created by another SecretSync meanwhile",
+			secretObjectName: "shared-secret",
+			existingSecret:   "unrelated",
+			createdMeanwhile: true,
+			expectedError:    true,
+			expectedReason:   ConditionReasonSecretNameConflict,
+		},
+		{
+			name:               "orphaned secret is taken over",
+			secretObjectName:   "orphaned-secret",
+			existingSecret:     "orphaned-secret",
+			orphaned:           true,
+			expectedReason:     ConditionReasonCreateSuccessful,
+			expectedSecretName: "orphaned-secret",
+		},
+		{
+			name:               "rename deletes the previous secret",
+			secretObjectName:   "new-secret",
+			syncedSecretName:   "old-secret",
+			existingSecret:     "old-secret",
+			existingSecretUID:  "ss-uid",
+			expectedReason:     ConditionReasonSecretUpToDate,
+			expectedSecretName: "new-secret",
+			expectedDeleted:    "old-secret",
+		},
+	}
+
+	scheme := setupScheme(t)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spc := &secretsstorecsiv1.SecretProviderClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-spc",
+					Namespace: "default",
+				},
+				Spec: secretsstorecsiv1.SecretProviderClassSpec{
+					Provider: "fake-provider",
+					Parameters: map[string]string{
+						"foo": "v1",
+					},
+				},
+			}
+			ss := &secretsyncv1alpha1.SecretSync{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "sse2esecret",
+					Namespace: "default",
+					UID:       "ss-uid",
+				},
+				Spec: secretsyncv1alpha1.SecretSyncSpec{
+					ServiceAccountName:      "default",
+					SecretProviderClassName: "test-spc",
+					SecretObject: secretsyncv1alpha1.SecretObject{
+						Name: test.secretObjectName,
+						Type: "Opaque",
+						Data: []secretsyncv1alpha1.SecretObjectData{
+							{
+								SourcePath: "foo",
+								TargetKey:  "bar",
+							},
+						},
+					},
+				},
+			}
+			if len(test.syncedSecretName) > 0 {
+				ss.Status.SecretName = test.syncedSecretName
+				ss.Status.SyncHash = "previous-hash"
+			}
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      test.existingSecret,
+					Namespace: "default",
+				},
+			}
+			if len(test.existingSecretUID) > 0 {
+				secret.Labels = map[string]string{controllerLabelKey: ""}
+				secret.OwnerReferences = []metav1.OwnerReference{
+					{
+						APIVersion: "secret-sync.x-k8s.io/v1alpha1",
+						Kind:       "SecretSync",
+						Name:       "other",
+						UID:        test.existingSecretUID,
+					},
+				}
+			}
+
+			if test.orphaned {
+				secret.Labels = map[string]string{controllerLabelKey: ""}
+			}
+
+			testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, spc, ss, secret)
+			ssc := testSecretSyncReconciler.secretSyncReconciler
+
+			if test.createdMeanwhile {
+				// another SecretSync creates the secret right before the controller
+				kubeClient := ssc.Clientset.(*fakeclient.Clientset)
+				kubeClient.PrependReactor("create", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+					other := &corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      test.secretObjectName,
+							Namespace: "default",
+							Labels:    map[string]string{controllerLabelKey: ""},
+							OwnerReferences: []metav1.OwnerReference{
+								{
+									APIVersion: "secret-sync.x-k8s.io/v1alpha1",
+									Kind:       "SecretSync",
+									Name:       "other",
+									UID:        "other-uid",
+								},
+							},
+						},
+					}
+					if err := kubeClient.Tracker().Add(other); err != nil && !apierrors.IsAlreadyExists(err) {
+						return true, nil, err
+					}
+					return false, nil, nil
+				})
+			}
+
+			req := ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "sse2esecret",
+					Namespace: "default",
+				},
+			}
+
+			_, err := ssc.Reconcile(context.Background(), req)
+			if test.expectedError != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", test.expectedError, err)
+			}
+
+			got := getSecretSyncObject(t, ssc, req)
+			conditionType := ConditionTypeCreate
+			if len(test.syncedSecretName) > 0 {
+				conditionType = ConditionTypeUpdate
+			}
+			if cond := meta.FindStatusCondition(got.Status.Conditions, conditionType); cond == nil || cond.Reason != test.expectedReason {
+				t.Fatalf("expected condition %s with reason %q, got %v", conditionType, test.expectedReason, got.Status.Conditions)
+			}
+			if got.Status.SecretName != test.expectedSecretName {
+				t.Errorf("expected status.secretName %q, got %q", test.expectedSecretName, got.Status.SecretName)
+			}
+
+			if len(test.expectedSecretName) > 0 {
+				synced, err := ssc.Clientset.CoreV1().Secrets("default").Get(context.Background(), test.expectedSecretName, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("expected secret %q to be synced, got error: %v", test.expectedSecretName, err)
+				}
+				if synced.OwnerReferences[0].Name != "sse2esecret" {
+					t.Errorf("expected secret to be owned by the SecretSync, got %v", synced.OwnerReferences)
+				}
+				// the later applies own every field of the claimed secret
+				for _, entry := range synced.ManagedFields {
+					if entry.Manager == secretSyncControllerFieldManager && entry.Operation != metav1.ManagedFieldsOperationApply {
+						t.Errorf("expected the fields of the secret to be applied, got %v", synced.ManagedFields)
+					}
+				}
+			}
+
+			if len(test.expectedDeleted) > 0 {
+				if _, err := ssc.Clientset.CoreV1().Secrets("default").Get(context.Background(), test.expectedDeleted, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+					t.Errorf("expected secret %q to be deleted, got %v", test.expectedDeleted, err)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileMissingKeyPolicy(t *testing.T) {
+	tests := []struct {
+		name             string
+		missingKeyPolicy secretsyncv1alpha1.MissingKeyPolicy
+		optional         bool
+		expectedError    bool
+		expectedReason   string
+		expectedMessage  string
+		expectedData     map[string][]byte
+	}{
+		{
+			name:          "missing key fails by default",
+			expectedError: true,
+		},
+		{
+			name:            "optional key is skipped",
+			optional:        true,
+			expectedReason:  ConditionReasonKeysSkipped,
+			expectedMessage: "The files of the following keys are missing from the provider response; skipped: gone.",
+			expectedData:    map[string][]byte{"bar": []byte("foo")},
+		},
+		{
+			name:             "skip policy",
+			missingKeyPolicy: secretsyncv1alpha1.MissingKeyPolicySkip,
+			expectedReason:   ConditionReasonKeysSkipped,
+			expectedMessage:  "The files of the following keys are missing from the provider response; skipped: gone.",
+			expectedData:     map[string][]byte{"bar": []byte("foo")},
+		},
+		{
+			name:             "keep previous policy",
+			missingKeyPolicy: secretsyncv1alpha1.MissingKeyPolicyKeepPrevious,
+			expectedReason:   ConditionReasonPreviousValuesKept,
+			expectedMessage:  "The files of the following keys are missing from the provider response; kept previous value: gone.",
+			expectedData:     map[string][]byte{"bar": []byte("foo"), "gone": []byte("previous")},
+		},
+	}
+
+	scheme := setupScheme(t)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spc := &secretsstorecsiv1.SecretProviderClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-spc",
+					Namespace: "default",
+				},
+				Spec: secretsstorecsiv1.SecretProviderClassSpec{
+					Provider: "fake-provider",
+					Parameters: map[string]string{
+						"foo": "v1",
+					},
+				},
+			}
+			ss := &secretsyncv1alpha1.SecretSync{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "sse2esecret",
+					Namespace: "default",
+				},
+				Spec: secretsyncv1alpha1.SecretSyncSpec{
+					ServiceAccountName:      "default",
+					SecretProviderClassName: "test-spc",
+					MissingKeyPolicy:        test.missingKeyPolicy,
+					SecretObject: secretsyncv1alpha1.SecretObject{
+						Type: "Opaque",
+						Data: []secretsyncv1alpha1.SecretObjectData{
+							{
+								SourcePath: "foo",
+								TargetKey:  "bar",
+							},
+							{
+								SourcePath: "missing",
+								TargetKey:  "gone",
+								Optional:   test.optional,
+							},
+						},
+					},
+				},
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "sse2esecret",
+					Namespace: "default",
+					Labels: map[string]string{
+						controllerLabelKey: "",
+					},
+				},
+				Data: map[string][]byte{
+					"gone": []byte("previous"),
+				},
+			}
+
+			testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, spc, ss, secret)
+			ssc := testSecretSyncReconciler.secretSyncReconciler
+
+			req := ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "sse2esecret",
+					Namespace: "default",
+				},
+			}
+
+			_, err := ssc.Reconcile(context.Background(), req)
+			if test.expectedError {
+				if err == nil || !strings.Contains(err.Error(), "file matching sourcePath missing not found") {
+					t.Fatalf("expected missing file error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := getSecretSyncObject(t, ssc, req)
+			cond := meta.FindStatusCondition(got.Status.Conditions, ConditionTypeKeysMissing)
+			if cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != test.expectedReason || cond.Message != test.expectedMessage {
+				t.Fatalf("unexpected %s condition: %v", ConditionTypeKeysMissing, cond)
+			}
+
+			synced, err := ssc.Clientset.CoreV1().Secrets("default").Get(context.Background(), "sse2esecret", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for key, value := range test.expectedData {
+				if got := synced.Data[key]; !bytes.Equal(got, value) {
+					t.Errorf("expected secret key %s to be %q, got %q", key, value, got)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileKeepPreviousExplodedKeys(t *testing.T) {
+	secretProviderClassToProcess := &secretsstorecsiv1.SecretProviderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-spc",
+			Namespace: "default",
+		},
+		Spec: secretsstorecsiv1.SecretProviderClassSpec{
+			Provider: "fake-provider",
+			Parameters: map[string]string{
+				"foo": "v1",
+			},
+		},
+	}
+	secretSyncToProcess := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+			UID:       "ss-uid",
+		},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			ServiceAccountName:      "default",
+			SecretProviderClassName: "test-spc",
+			MissingKeyPolicy:        secretsyncv1alpha1.MissingKeyPolicyKeepPrevious,
+			SecretObject: secretsyncv1alpha1.SecretObject{
+				Type: "Opaque",
+				Data: []secretsyncv1alpha1.SecretObjectData{
+					{
+						SourcePath: "foo",
+						TargetKey:  "bar",
+					},
+					{
+						SourcePath: "credentials.json",
+						TargetKey:  "credentials",
+						Explode:    true,
+					},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "unrelated",
+			Namespace: "default",
+		},
+	}
+
+	scheme := setupScheme(t)
+	testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, secretProviderClassToProcess, secretSyncToProcess, secret)
+	ssc := testSecretSyncReconciler.secretSyncReconciler
+	testSecretSyncReconciler.fakeProviderServer.SetFiles([]*v1alpha1.File{
+		{Path: "foo", Mode: 0644, Contents: []byte("foo")},
+		{Path: "credentials.json", Mode: 0644, Contents: []byte(`{"username":"admin","password":"s3cr3t"}`)},
+	})
+
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+	}
+	if _, err := ssc.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the exploded file is missing from the next provider response
+	testSecretSyncReconciler.fakeProviderServer.SetFiles([]*v1alpha1.File{
+		{Path: "foo", Mode: 0644, Contents: []byte("bar")},
+	})
+	testSecretSyncReconciler.fakeProviderServer.SetObjects(map[string]string{"secret/object1": "v2"})
+	if _, err := ssc.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := getSecretSyncObject(t, ssc, req)
+	cond := meta.FindStatusCondition(got.Status.Conditions, ConditionTypeKeysMissing)
+	expectedMessage := "The files of the following keys are missing from the provider response; kept previous value: credentials.json."
+	if cond == nil || cond.Reason != ConditionReasonPreviousValuesKept || cond.Message != expectedMessage {
+		t.Fatalf("unexpected %s condition: %v", ConditionTypeKeysMissing, cond)
+	}
+
+	synced, err := ssc.Clientset.CoreV1().Secrets("default").Get(context.Background(), "sse2esecret", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedData := map[string][]byte{
+		"bar":      []byte("bar"),
+		"username": []byte("admin"),
+		"password": []byte("s3cr3t"),
+	}
+	if !reflect.DeepEqual(synced.Data, expectedData) {
+		t.Errorf("expected secret data %q, got %q", expectedData, synced.Data)
+	}
+}
+
+func TestReconcileEvents(t *testing.T) {
+	secretProviderClassToProcess := &secretsstorecsiv1.SecretProviderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-spc",
+			Namespace: "default",
+		},
+		Spec: secretsstorecsiv1.SecretProviderClassSpec{
+			Provider: "fake-provider",
+			Parameters: map[string]string{
+				"foo": "v1",
+			},
+		},
+	}
+	secretSyncToProcess := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			ServiceAccountName:      "default",
+			SecretProviderClassName: "test-spc",
+			SecretObject: secretsyncv1alpha1.SecretObject{
+				Type: "Opaque",
+				Data: []secretsyncv1alpha1.SecretObjectData{
+					{
+						SourcePath: "foo",
+						TargetKey:  "bar",
+					},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+			Labels: map[string]string{
+				controllerLabelKey: "",
+			},
+		},
+	}
+
+	scheme := setupScheme(t)
+	testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, secretProviderClassToProcess, secretSyncToProcess, secret)
+	ssc := testSecretSyncReconciler.secretSyncReconciler
+	recorder := ssc.EventRecorder.(*record.FakeRecorder)
+
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+	}
+
+	reconcileAndExpectEvents := func(expected ...string) {
+		t.Helper()
+
+		_, _ = ssc.Reconcile(context.Background(), req)
+		if events := drainEvents(recorder); !reflect.DeepEqual(events, expected) {
+			t.Fatalf("expected events %q, got %q", expected, events)
+		}
+	}
+
+	reconcileAndExpectEvents(`Normal CreateSuccessful Secret "sse2esecret" created`)
+	reconcileAndExpectEvents(`Normal SecretUpToDate Secret "sse2esecret" is up to date, no change detected`)
+
+	// a changed file is synced again
+	testSecretSyncReconciler.fakeProviderServer.SetFiles([]*v1alpha1.File{
+		{
+			Path:     "foo",
+			Mode:     0644,
+			Contents: []byte("changed"),
+		},
+	})
+	testSecretSyncReconciler.fakeProviderServer.SetObjects(map[string]string{"secret/object1": "v2"})
+	reconcileAndExpectEvents(`Normal SecretUpToDate Secret "sse2esecret" updated`)
+
+	// the file is gone
+	testSecretSyncReconciler.fakeProviderServer.SetFiles([]*v1alpha1.File{})
+	reconcileAndExpectEvents(`Warning RemoteSecretStoreFetchFailed fetching secrets from the provider failed: file matching sourcePath foo not found in the pod`)
+
+	// the SecretProviderClass is gone
+	if err := ssc.Delete(context.Background(), secretProviderClassToProcess); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reconcileAndExpectEvents(`Warning SecretProviderClassMisconfigured SecretProviderClass "test-spc" does not exist in namespace "default"`)
+}
+
+func TestReconcileProviderUnavailable(t *testing.T) {
+	secretProviderClassToProcess := &secretsstorecsiv1.SecretProviderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-spc",
+			Namespace: "default",
+		},
+		Spec: secretsstorecsiv1.SecretProviderClassSpec{
+			Provider: "fake-provider",
+			Parameters: map[string]string{
+				"foo": "v1",
+			},
+		},
+	}
+	secretSyncToProcess := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			ServiceAccountName:      "default",
+			SecretProviderClassName: "test-spc",
+			SecretObject: secretsyncv1alpha1.SecretObject{
+				Type: "Opaque",
+				Data: []secretsyncv1alpha1.SecretObjectData{
+					{
+						SourcePath: "foo",
+						TargetKey:  "bar",
+					},
+				},
+			},
+		},
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+			Labels: map[string]string{
+				controllerLabelKey: "",
+			},
+		},
+	}
+
+	scheme := setupScheme(t)
+	testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, secretProviderClassToProcess, secretSyncToProcess, secret)
+	ssc := testSecretSyncReconciler.secretSyncReconciler
+	ssc.ProviderClients.(*provider.PluginClientBuilder).SetMountLimits(provider.MountLimits{
+		FailureThreshold: 1,
+		OpenDuration:     time.Hour,
+	})
+	testSecretSyncReconciler.fakeProviderServer.SetReturnError(status.Error(codes.Internal, "provider is down"))
+
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+	}
+	reconcileAndExpectReason := func(expected string) {
+		t.Helper()
+
+		if _, err := ssc.Reconcile(context.Background(), req); err == nil {
+			t.Fatal("expected an error")
+		}
+		ss := &secretsyncv1alpha1.SecretSync{}
+		if err := ssc.Get(context.Background(), req.NamespacedName, ss); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if condition := meta.FindStatusCondition(ss.Status.Conditions, ConditionTypeCreate); condition == nil || condition.Reason != expected {
+			t.Fatalf("expected the %s reason, got %v", expected, condition)
+		}
+	}
+
+	// the failure opens the circuit breaker, the next sync fails fast
+	reconcileAndExpectReason(ConditionReasonFailedProviderError)
+	reconcileAndExpectReason(ConditionReasonProviderUnavailable)
+}
+
+func TestReconcileRetries(t *testing.T) {
+	secretProviderClassToProcess := &secretsstorecsiv1.SecretProviderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-spc",
+			Namespace: "default",
+		},
+		Spec: secretsstorecsiv1.SecretProviderClassSpec{
+			Provider: "fake-provider",
+			Parameters: map[string]string{
+				"foo": "v1",
+			},
+		},
+	}
+	secretSyncToProcess := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			ServiceAccountName:      "default",
+			SecretProviderClassName: "test-spc",
+			SecretObject: secretsyncv1alpha1.SecretObject{
+				Type: "Opaque",
+				Data: []secretsyncv1alpha1.SecretObjectData{
+					{
+						SourcePath: "foo",
+						TargetKey:  "bar",
+					},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+			Labels: map[string]string{
+				controllerLabelKey: "",
+			},
+		},
+	}
+
+	scheme := setupScheme(t)
+	testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, secretProviderClassToProcess, secretSyncToProcess, secret)
+	ssc := testSecretSyncReconciler.secretSyncReconciler
+	now := time.Date(2024, time.January, 10, 10, 30, 0, 0, time.UTC)
+	ssc.Scheduler = newRotationScheduler(0, 0, clocktesting.NewFakeClock(now))
+	ssc.RetryBackoff = RetryBackoff{Min: 2 * time.Second, Max: 5 * time.Second}
+	ssc.retryLimiter = newRetryRateLimiter(ssc.RetryBackoff)
+
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+	}
+	reconcileAndExpectStatus := func(expectTerminal bool, expectedFailureCount int32, expectedRetryAfter time.Duration) {
+		t.Helper()
+
+		_, err := ssc.Reconcile(context.Background(), req)
+		if expectedFailureCount == 0 {
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		} else if err == nil || errors.Is(err, reconcile.TerminalError(nil)) != expectTerminal {
+			t.Fatalf("expected a terminal error %t, got %v", expectTerminal, err)
+		}
+
+		ss := getSecretSyncObject(t, ssc, req)
+		if ss.Status.LastAttemptTime == nil || !ss.Status.LastAttemptTime.Time.Equal(now) {
+			t.Fatalf("expected the last attempt at %s, got %v", now, ss.Status.LastAttemptTime)
+		}
+		if ss.Status.FailureCount != expectedFailureCount {
+			t.Fatalf("expected %d failures, got %d", expectedFailureCount, ss.Status.FailureCount)
+		}
+		if expectedRetryAfter == 0 {
+			if ss.Status.NextRetryTime != nil {
+				t.Fatalf("expected no retry, got %v", ss.Status.NextRetryTime)
+			}
+			return
+		}
+		if expected := now.Add(expectedRetryAfter).Truncate(time.Second); ss.Status.NextRetryTime == nil || !ss.Status.NextRetryTime.Time.Equal(expected) {
+			t.Fatalf("expected the next retry at %s, got %v", expected, ss.Status.NextRetryTime)
+		}
+		if delay := ssc.retryLimiter.When(req); delay != expectedRetryAfter {
+			t.Fatalf("expected a retry after %s, got %s", expectedRetryAfter, delay)
+		}
+	}
+
+	// an unavailable provider is retried with an exponential backoff
+	testSecretSyncReconciler.fakeProviderServer.SetReturnError(status.Error(codes.Unavailable, "provider is down"))
+	reconcileAndExpectStatus(false, 1, 2*time.Second)
+	reconcileAndExpectStatus(false, 2, 4*time.Second)
+	reconcileAndExpectStatus(false, 3, 5*time.Second)
+
+	// without rotation, a denied access waits for a change of the SecretSync or
+	// the SecretProviderClass
+	testSecretSyncReconciler.fakeProviderServer.SetReturnError(status.Error(codes.PermissionDenied, "access denied"))
+	reconcileAndExpectStatus(true, 4, 0)
+
+	// it is retried at the next rotation otherwise
+	ssc.rotationPollInterval = time.Hour
+	rotation, err := parseRotation(nil, ssc.rotationPollInterval)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reconcileAndExpectStatus(false, 5, rotation.next(now, rotationSpread(secretSyncToProcess.UID)).Sub(now))
+	ssc.rotationPollInterval = 0
+
+	// a successful sync resets the failures
+	testSecretSyncReconciler.fakeProviderServer.SetReturnError(nil)
+	reconcileAndExpectStatus(false, 0, 0)
+
+	// a provider client which cannot be built is retried with a backoff
+	providerClients := ssc.ProviderClients
+	ssc.ProviderClients = &failingClientBuilder{AllClientBuilder: providerClients, err: errors.New("failed to read the client certificate")}
+	reconcileAndExpectStatus(false, 1, 2*time.Second)
+	if condition := meta.FindStatusCondition(getSecretSyncObject(t, ssc, req).Status.Conditions, ConditionTypeUpdate); condition == nil || condition.Reason != ConditionReasonProviderUnavailable {
+		t.Fatalf("expected the %s reason, got %v", ConditionReasonProviderUnavailable, condition)
+	}
+
+	// the retry of a deleted SecretSync is dropped by its finalizer
+	if _, err := ssc.Reconcile(context.Background(), req); err == nil {
+		t.Fatal("expected the sync to fail")
+	}
+	if err := ssc.Delete(context.Background(), getSecretSyncObject(t, ssc, req)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ssc.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ssc.retryLimiter.delays) != 0 {
+		t.Fatalf("expected no retry left, got %v", ssc.retryLimiter.delays)
+	}
+}
+
+// failingClientBuilder fails to build the provider clients.
+type failingClientBuilder struct {
+	AllClientBuilder
+	err error
+}
+
+func (b *failingClientBuilder) Get(ctx context.Context, provider string) (v1alpha1.CSIDriverProviderClient, error) {
+	return nil, b.err
+}
+
+// recordingClientBuilder records the Mount requests sent to the providers.
+type recordingClientBuilder struct {
+	AllClientBuilder
+	requests []*v1alpha1.MountRequest
+}
+
+func (b *recordingClientBuilder) Get(ctx context.Context, provider string) (v1alpha1.CSIDriverProviderClient, error) {
+	c, err := b.AllClientBuilder.Get(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingClient{CSIDriverProviderClient: c, builder: b}, nil
+}
+
+type recordingClient struct {
+	v1alpha1.CSIDriverProviderClient
+	builder *recordingClientBuilder
+}
+
+func (c *recordingClient) Mount(ctx context.Context, req *v1alpha1.MountRequest, opts ...grpc.CallOption) (*v1alpha1.MountResponse, error) {
+	c.builder.requests = append(c.builder.requests, req)
+	return c.CSIDriverProviderClient.Mount(ctx, req, opts...)
+}
+
+func TestReconcileObjectVersions(t *testing.T) {
+	secretProviderClassToProcess := &secretsstorecsiv1.SecretProviderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-spc",
+			Namespace: "default",
+		},
+		Spec: secretsstorecsiv1.SecretProviderClassSpec{
+			Provider: "fake-provider",
+			Parameters: map[string]string{
+				"foo": "v1",
+			},
+		},
+	}
+	secretSyncToProcess := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			ServiceAccountName:      "default",
+			SecretProviderClassName: "test-spc",
+			SecretObject: secretsyncv1alpha1.SecretObject{
+				Type: "Opaque",
+				Data: []secretsyncv1alpha1.SecretObjectData{
+					{
+						SourcePath: "foo",
+						TargetKey:  "bar",
+					},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+			Labels: map[string]string{
+				controllerLabelKey: "",
+			},
+		},
+	}
+
+	scheme := setupScheme(t)
+	testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, secretProviderClassToProcess, secretSyncToProcess, secret)
+	ssc := testSecretSyncReconciler.secretSyncReconciler
+	providerClients := &recordingClientBuilder{AllClientBuilder: ssc.ProviderClients}
+	ssc.ProviderClients = providerClients
+	recorder := ssc.EventRecorder.(*record.FakeRecorder)
+	fakeProviderServer := testSecretSyncReconciler.fakeProviderServer
+
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+	}
+	reconcileAndExpectEvent := func(expected string) {
+		t.Helper()
+
+		if _, err := ssc.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if events := drainEvents(recorder); !reflect.DeepEqual(events, []string{expected}) {
+			t.Fatalf("expected events %q, got %q", expected, events)
+		}
+	}
+	setFile := func(contents string) {
+		fakeProviderServer.SetFiles([]*v1alpha1.File{{Path: "foo", Mode: 0644, Contents: []byte(contents)}})
+	}
+	lastObjectVersions := func() []*v1alpha1.ObjectVersion {
+		return providerClients.requests[len(providerClients.requests)-1].CurrentObjectVersion
+	}
+
+	expectObjectVersions := func(expected map[string]string) {
+		t.Helper()
+
+		if objectVersions := getSecretSyncObject(t, ssc, req).Status.ObjectVersions; !reflect.DeepEqual(objectVersions, expected) {
+			t.Fatalf("expected object versions %v, got %v", expected, objectVersions)
+		}
+	}
+
+	reconcileAndExpectEvent(`Normal CreateSuccessful Secret "sse2esecret" created`)
+	if sent := lastObjectVersions(); len(sent) > 0 {
+		t.Fatalf("expected no object versions to be sent, got %v", sent)
+	}
+	expectObjectVersions(map[string]string{"secret/object1": "v1"})
+
+	// the versions of the last sync are sent back to the provider
+	reconcileAndExpectEvent(`Normal SecretUpToDate Secret "sse2esecret" is up to date, no change detected`)
+	if sent := lastObjectVersions(); len(sent) != 1 || sent[0].Id != "secret/object1" || sent[0].Version != "v1" {
+		t.Fatalf("expected the object versions of the last sync to be sent, got %v", sent)
+	}
+
+	// a new version is synced
+	fakeProviderServer.SetObjects(map[string]string{"secret/object1": "v2"})
+	setFile("v2")
+	reconcileAndExpectEvent(`Normal SecretUpToDate Secret "sse2esecret" updated`)
+	expectObjectVersions(map[string]string{"secret/object1": "v2"})
+
+	// the versions are only trusted once the controller synced the SecretSync
+	// itself, e.g. not after a restart
+	ssc.lastSyncedInputs = nil
+	setFile("not reported by the provider")
+	reconcileAndExpectEvent(`Normal SecretUpToDate Secret "sse2esecret" updated`)
+
+	// new content under unchanged versions is neither hashed nor applied
+	setFile("not reported by the provider either")
+	reconcileAndExpectEvent(`Normal SecretUpToDate Secret "sse2esecret" is up to date, no change detected`)
+	synced, err := ssc.Clientset.CoreV1().Secrets("default").Get(context.Background(), "sse2esecret", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := string(synced.Data["bar"]); got != "not reported by the provider" {
+		t.Fatalf("expected the secret data of the last versions, got %q", got)
+	}
+}
+
+func TestReconcileSkipsHashOnUnchangedObjectVersions(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	previousTracerProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousTracerProvider)
+	})
+
+	secretProviderClassToProcess := &secretsstorecsiv1.SecretProviderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-spc",
+			Namespace: "default",
+		},
+		Spec: secretsstorecsiv1.SecretProviderClassSpec{
+			Provider: "fake-provider",
+			Parameters: map[string]string{
+				"foo": "v1",
+			},
+		},
+	}
+	secretSyncToProcess := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			ServiceAccountName:      "default",
+			SecretProviderClassName: "test-spc",
+			SecretObject: secretsyncv1alpha1.SecretObject{
+				Type: "Opaque",
+				Data: []secretsyncv1alpha1.SecretObjectData{
+					{
+						SourcePath: "foo",
+						TargetKey:  "bar",
+					},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+			Labels: map[string]string{
+				controllerLabelKey: "",
+			},
+		},
+	}
+
+	scheme := setupScheme(t)
+	testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, secretProviderClassToProcess, secretSyncToProcess, secret)
+	ssc := testSecretSyncReconciler.secretSyncReconciler
+	fakeProviderServer := testSecretSyncReconciler.fakeProviderServer
+
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+	}
+	reconcileAndExpectHash := func(expected bool) {
+		t.Helper()
+
+		spanRecorder.Reset()
+		if _, err := ssc.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		hashed := slices.ContainsFunc(spanRecorder.Ended(), func(span sdktrace.ReadOnlySpan) bool {
+			return span.Name() == "computeCurrentStateHash"
+		})
+		if hashed != expected {
+			t.Fatalf("expected the state hash to be computed: %t, got %t", expected, hashed)
+		}
+	}
+	setFile := func(contents string) {
+		fakeProviderServer.SetFiles([]*v1alpha1.File{{Path: "foo", Mode: 0644, Contents: []byte(contents)}})
+	}
+
+	reconcileAndExpectHash(true)
+
+	// the provider returns the versions of the last sync
+	reconcileAndExpectHash(false)
+	reconcileAndExpectHash(false)
+
+	// new content under the same versions is not synced
+	setFile("changed")
+	reconcileAndExpectHash(false)
+
+	// new versions
+	fakeProviderServer.SetObjects(map[string]string{"secret/object1": "v2"})
+	reconcileAndExpectHash(true)
+	reconcileAndExpectHash(false)
+
+	// the versions are only trusted once the controller synced the SecretSync
+	// itself
+	ssc.lastSyncedInputs = nil
+	reconcileAndExpectHash(true)
+}
+
+func TestReconcileProviderSecret(t *testing.T) {
+	secretProviderClassToProcess := &secretsstorecsiv1.SecretProviderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-spc",
+			Namespace: "default",
+		},
+		Spec: secretsstorecsiv1.SecretProviderClassSpec{
+			Provider: "fake-provider",
+			Parameters: map[string]string{
+				"foo": "v1",
+			},
+		},
+	}
+	secretSyncToProcess := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			ServiceAccountName:      "default",
+			SecretProviderClassName: "test-spc",
+			ProviderSecretRef: &secretsyncv1alpha1.ProviderSecretRef{
+				Name: "provider-credentials",
+			},
+			SecretObject: secretsyncv1alpha1.SecretObject{
+				Type: "Opaque",
+				Data: []secretsyncv1alpha1.SecretObjectData{
+					{
+						SourcePath: "foo",
+						TargetKey:  "bar",
+					},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+			Labels: map[string]string{
+				controllerLabelKey: "",
+			},
+		},
+	}
+
+	scheme := setupScheme(t)
+	testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, secretProviderClassToProcess, secretSyncToProcess, secret)
+	ssc := testSecretSyncReconciler.secretSyncReconciler
+	providerClients := &recordingClientBuilder{AllClientBuilder: ssc.ProviderClients}
+	ssc.ProviderClients = providerClients
+
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+	}
+	reconcileAndExpectReason := func(expected string) {
+		t.Helper()
+
+		_, _ = ssc.Reconcile(context.Background(), req)
+		ss := getSecretSyncObject(t, ssc, req)
+		if condition := meta.FindStatusCondition(ss.Status.Conditions, ConditionTypeCreate); condition == nil || condition.Reason != expected {
+			t.Fatalf("expected the %s reason, got %v", expected, condition)
+		}
+	}
+
+	// the secret does not exist
+	reconcileAndExpectReason(ConditionReasonProviderSecretError)
+
+	// the secret is not labeled as used by the provider
+	providerSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "provider-credentials",
+			Namespace:       "default",
+			UID:             "provider-credentials-uid",
+			ResourceVersion: "1",
+		},
+		Data: map[string][]byte{
+			"clientid": []byte("id"),
+		},
+	}
+	providerSecret, err := ssc.Clientset.CoreV1().Secrets("default").Create(context.Background(), providerSecret, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reconcileAndExpectReason(ConditionReasonProviderSecretError)
+	if len(providerClients.requests) > 0 {
+		t.Fatalf("expected no Mount request, got %d", len(providerClients.requests))
+	}
+
+	// the data of the labeled secret is sent to the provider
+	providerSecret.Labels = map[string]string{ProviderSecretLabelKey: ProviderSecretLabelValue}
+	if providerSecret, err = ssc.Clientset.CoreV1().Secrets("default").Update(context.Background(), providerSecret, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reconcileAndExpectReason(ConditionReasonCreateSuccessful)
+	if len(providerClients.requests) != 1 || providerClients.requests[0].Secrets != `{"clientid":"id"}` {
+		t.Fatalf("expected the provider secret to be sent, got %v", providerClients.requests)
+	}
+
+	// changing the credentials changes the hash
+	hash := getSecretSyncObject(t, ssc, req).Status.SyncHash
+	providerSecret.Data["clientid"] = []byte("rotated")
+	providerSecret.ResourceVersion = "2"
+	if _, err = ssc.Clientset.CoreV1().Secrets("default").Update(context.Background(), providerSecret, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reconcileAndExpectReason(ConditionReasonCreateSuccessful)
+	if sent := providerClients.requests[len(providerClients.requests)-1].Secrets; sent != `{"clientid":"rotated"}` {
+		t.Fatalf("expected the new provider secret to be sent, got %s", sent)
+	}
+	if newHash := getSecretSyncObject(t, ssc, req).Status.SyncHash; newHash == hash {
+		t.Fatalf("expected the hash to change with the provider secret")
+	}
+}
+
+func TestReconcileRotation(t *testing.T) {
+	secretProviderClassToProcess := &secretsstorecsiv1.SecretProviderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-spc",
+			Namespace: "default",
+		},
+		Spec: secretsstorecsiv1.SecretProviderClassSpec{
+			Provider: "fake-provider",
+			Parameters: map[string]string{
+				"foo": "v1",
+			},
+		},
+	}
+	secretSyncToProcess := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+			UID:       "d6c1f0a2-0000-4000-8000-000000000001",
+		},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			ServiceAccountName:      "default",
+			SecretProviderClassName: "test-spc",
+			Rotation: &secretsyncv1alpha1.Rotation{
+				Interval: &metav1.Duration{Duration: 5 * time.Minute},
+			},
+			SecretObject: secretsyncv1alpha1.SecretObject{
+				Type: "Opaque",
+				Data: []secretsyncv1alpha1.SecretObjectData{
+					{
+						SourcePath: "foo",
+						TargetKey:  "bar",
+					},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+			Labels: map[string]string{
+				controllerLabelKey: "",
+			},
+		},
+	}
+
+	scheme := setupScheme(t)
+	testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, secretProviderClassToProcess, secretSyncToProcess, secret)
+	ssc := testSecretSyncReconciler.secretSyncReconciler
+	ssc.rotationPollInterval = 12 * time.Hour
+	clock := clocktesting.NewFakeClock(time.Date(2024, time.January, 10, 10, 30, 0, 0, time.UTC))
+	ssc.Scheduler = newRotationScheduler(0, 0, clock)
+
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+	}
+	// the SecretSync is synced at its offset in the interval, derived from its
+	// UID
+	nextSlot := func(interval time.Duration) time.Time {
+		now := clock.Now()
+		next := now.Add(-time.Duration(now.UnixNano() % int64(interval))).Add(time.Duration(rotationSpread(secretSyncToProcess.UID) % uint64(interval)))
+		if !next.After(now) {
+			next = next.Add(interval)
+		}
+		return next
+	}
+	reconcileAndExpectRequeue := func(expected time.Time) {
+		t.Helper()
+
+		result, err := ssc.Reconcile(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if requeueAfter := expected.Sub(clock.Now()); result.RequeueAfter != requeueAfter {
+			t.Fatalf("expected a requeue after %s, got %s", requeueAfter, result.RequeueAfter)
+		}
+		if nextSyncTime := getSecretSyncObject(t, ssc, req).Status.NextSyncTime; nextSyncTime == nil || !nextSyncTime.Time.Equal(expected.Truncate(time.Second)) {
+			t.Fatalf("expected the next sync at %s, got %v", expected, nextSyncTime)
+		}
+	}
+
+	// the rotation of the SecretSync overrides the poll interval, on create and
+	// when the secret is up to date
+	reconcileAndExpectRequeue(nextSlot(5 * time.Minute))
+	clock.SetTime(nextSlot(5 * time.Minute))
+	reconcileAndExpectRequeue(nextSlot(5 * time.Minute))
+	if depth := ssc.Scheduler.depth(); depth.scheduled != 1 {
+		t.Fatalf("expected 1 scheduled SecretSync, got %v", depth)
+	}
+
+	// the provider is not called again before the next sync, e.g. after a
+	// restart
+	providerClients := &recordingClientBuilder{AllClientBuilder: ssc.ProviderClients}
+	ssc.ProviderClients = providerClients
+	ssc.lastSyncedInputs = nil
+	ssc.appliedSecrets = nil
+	clock.Step(time.Minute)
+	result, err := ssc.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(providerClients.requests) != 0 {
+		t.Fatalf("expected no provider call before the next sync, got %d", len(providerClients.requests))
+	}
+	if nextSyncTime := getSecretSyncObject(t, ssc, req).Status.NextSyncTime; result.RequeueAfter != nextSyncTime.Sub(clock.Now()) {
+		t.Fatalf("expected a requeue at the next sync %s, got %s", nextSyncTime, result.RequeueAfter)
+	}
+
+	// without rotation the SecretSync is synced every poll interval
+	ss := getSecretSyncObject(t, ssc, req)
+	ss.Spec.Rotation = nil
+	ss.Generation++ // bumped by the API server
+	if err := ssc.Update(context.Background(), ss); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reconcileAndExpectRequeue(nextSlot(12 * time.Hour))
+
+	// an invalid rotation fails the sync
+	ss = getSecretSyncObject(t, ssc, req)
+	ss.Spec.Rotation = &secretsyncv1alpha1.Rotation{Schedule: "0 0 30 2 *"}
+	if err := ssc.Update(context.Background(), ss); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ssc.Reconcile(context.Background(), req); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if condition := meta.FindStatusCondition(getSecretSyncObject(t, ssc, req).Status.Conditions, ConditionTypeUpdate); condition == nil || condition.Reason != ConditionReasonInvalidRotation {
+		t.Fatalf("expected the %s reason, got %v", ConditionReasonInvalidRotation, condition)
+	}
+}
+
+func TestReconcileThrottled(t *testing.T) {
+	secretProviderClassToProcess := &secretsstorecsiv1.SecretProviderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-spc",
+			Namespace: "default",
+		},
+		Spec: secretsstorecsiv1.SecretProviderClassSpec{
+			Provider: "fake-provider",
+			Parameters: map[string]string{
+				"foo": "v1",
+			},
+		},
+	}
+	secretSyncToProcess := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			ServiceAccountName:      "default",
+			SecretProviderClassName: "test-spc",
+			SecretObject: secretsyncv1alpha1.SecretObject{
+				Type: "Opaque",
+				Data: []secretsyncv1alpha1.SecretObjectData{
+					{
+						SourcePath: "foo",
+						TargetKey:  "bar",
+					},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+			Labels: map[string]string{
+				controllerLabelKey: "",
+			},
+		},
+	}
+
+	scheme := setupScheme(t)
+	testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, secretProviderClassToProcess, secretSyncToProcess, secret)
+	ssc := testSecretSyncReconciler.secretSyncReconciler
+	clock := clocktesting.NewFakeClock(time.Date(2024, time.January, 10, 10, 30, 0, 0, time.UTC))
+	ssc.Scheduler = newRotationScheduler(1, 1, clock)
+
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+	}
+	if _, err := ssc.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the next sync is requeued once the rate limit allows it, without
+	// blocking the worker or failing the sync
+	testSecretSyncReconciler.fakeProviderServer.SetObjects(map[string]string{"secret/object1": "v2"})
+	result, err := ssc.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter != time.Second {
+		t.Fatalf("expected a requeue after 1s, got %s", result.RequeueAfter)
+	}
+	ss := getSecretSyncObject(t, ssc, req)
+	if ss.Status.FailureCount != 0 || ss.Status.ObjectVersions["secret/object1"] != "v1" {
+		t.Fatalf("expected the throttled sync not to change the status, got %+v", ss.Status)
+	}
+
+	clock.Step(time.Second)
+	if _, err := ssc.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if versions := getSecretSyncObject(t, ssc, req).Status.ObjectVersions; versions["secret/object1"] != "v2" {
+		t.Fatalf("expected the sync to call the provider, got the object versions %v", versions)
+	}
+}
+
+// fakeProvidersNotifier publishes the discovered providers on demand.
+type fakeProvidersNotifier struct {
+	listeners []provider.ProvidersListener
+}
+
+func (n *fakeProvidersNotifier) AddProvidersListener(listener provider.ProvidersListener) {
+	n.listeners = append(n.listeners, listener)
+}
+
+func (n *fakeProvidersNotifier) discover(providers, added []string) {
+	for _, listener := range n.listeners {
+		listener(providers, added)
+	}
+}
+
+func TestProviderDiscoveryRequeuesSecretSyncs(t *testing.T) {
+	newSecretSync := func(name, reason string) *secretsyncv1alpha1.SecretSync {
+		return &secretsyncv1alpha1.SecretSync{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Status: secretsyncv1alpha1.SecretSyncStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:   ConditionTypeCreate,
+						Status: metav1.ConditionFalse,
+						Reason: reason,
+					},
+				},
+			},
+		}
+	}
+
+	scheme := setupScheme(t)
+	ssc := &SecretSyncReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(
+				newSecretSync("waiting", ConditionReasonProviderNotFound),
+				newSecretSync("failed", ConditionReasonFailedProviderError),
+			).
+			Build(),
+	}
+
+	notifier := &fakeProvidersNotifier{}
+	providersChannel, discoveryFunc := ssc.providerDiscoveryFunc(notifier, &informertest.FakeInformers{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = discoveryFunc(ctx)
+	}()
+
+	// removed providers do not requeue anything
+	notifier.discover([]string{}, nil)
+	notifier.discover([]string{"fake-provider"}, []string{"fake-provider"})
+
+	select {
+	case e := <-providersChannel:
+		if e.Object.Name != "waiting" {
+			t.Fatalf("expected SecretSync waiting to be requeued, got %s", e.Object.Name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected SecretSync waiting to be requeued")
+	}
+
+	select {
+	case e := <-providersChannel:
+		t.Fatalf("expected no other SecretSync to be requeued, got %s", e.Object.Name)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestReconcileTracing(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	previousTracerProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousTracerProvider)
+	})
+
+	secretProviderClassToProcess := &secretsstorecsiv1.SecretProviderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-spc",
+			Namespace: "default",
+		},
+		Spec: secretsstorecsiv1.SecretProviderClassSpec{
+			Provider: "fake-provider",
+			Parameters: map[string]string{
+				"foo": "v1",
+			},
+		},
+	}
+	secretSyncToProcess := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			ServiceAccountName:      "default",
+			SecretProviderClassName: "test-spc",
+			SecretObject: secretsyncv1alpha1.SecretObject{
+				Type: "Opaque",
+				Data: []secretsyncv1alpha1.SecretObjectData{
+					{
+						SourcePath: "foo",
+						TargetKey:  "bar",
+					},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+			Labels: map[string]string{
+				controllerLabelKey: "",
+			},
+		},
+	}
+
+	scheme := setupScheme(t)
+	ssc := newSecretSyncReconciler(t, scheme, secretProviderClassToProcess, secretSyncToProcess, secret).secretSyncReconciler
+
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+	}
+	if _, err := ssc.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range spanRecorder.Ended() {
+		spans[span.Name()] = span
+	}
+	reconcileSpan, ok := spans["Reconcile"]
+	if !ok {
+		t.Fatalf("expected a Reconcile span, got %v", slices.Collect(maps.Keys(spans)))
+	}
+	for _, name := range []string{"prepareCSIProviderParams", "MountContent", "v1alpha1.CSIDriverProvider/Mount", "computeCurrentStateHash", "serverSidePatchSecret"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Fatalf("expected a %s span, got %v", name, slices.Collect(maps.Keys(spans)))
+		}
+		if span.SpanContext().TraceID() != reconcileSpan.SpanContext().TraceID() {
+			t.Errorf("expected the %s span to be part of the Reconcile trace", name)
+		}
+	}
+}
+
+func TestReconcileMetrics(t *testing.T) {
+	secretProviderClassToProcess := &secretsstorecsiv1.SecretProviderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-spc",
+			Namespace: "default",
+		},
+		Spec: secretsstorecsiv1.SecretProviderClassSpec{
+			Provider: "fake-provider",
+			Parameters: map[string]string{
+				"foo": "v1",
+			},
+		},
+	}
+	secretSyncToProcess := &secretsyncv1alpha1.SecretSync{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+		Spec: secretsyncv1alpha1.SecretSyncSpec{
+			ServiceAccountName:      "default",
+			SecretProviderClassName: "test-spc",
+			SecretObject: secretsyncv1alpha1.SecretObject{
+				Type: "Opaque",
+				Data: []secretsyncv1alpha1.SecretObjectData{
+					{
+						SourcePath: "foo",
+						TargetKey:  "bar",
+					},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sse2esecret",
+			Namespace: "default",
+			Labels: map[string]string{
+				controllerLabelKey: "",
+			},
+		},
+	}
+
+	scheme := setupScheme(t)
+	testSecretSyncReconciler := newSecretSyncReconciler(t, scheme, secretProviderClassToProcess, secretSyncToProcess, secret)
+	ssc := testSecretSyncReconciler.secretSyncReconciler
+
+	reader := sdkmetric.NewManualReader()
+	statsReporter, err := newStatsReporter(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter(scope))
+	if err != nil {
+		t.Fatalf("unexpected stats reporter failure: %v", err)
+	}
+	ssc.statsReporter = statsReporter
+
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "sse2esecret",
+			Namespace: "default",
+		},
+	}
+
+	_, _ = ssc.Reconcile(context.Background(), req)
+	_, _ = ssc.Reconcile(context.Background(), req)
+	testSecretSyncReconciler.fakeProviderServer.SetFiles([]*v1alpha1.File{})
+	_, _ = ssc.Reconcile(context.Background(), req)
+
+	lastSuccessfulSyncTime := getSecretSyncObject(t, ssc, req).Status.LastSuccessfulSyncTime.Time
+	statsReporter.now = func() time.Time {
+		return lastSuccessfulSyncTime.Add(30 * time.Second)
+	}
+
+	metrics := collectMetrics(t, reader)
+
+	syncTotal := map[string]int64{}
+	for _, dp := range metrics["secret_sync_total"].(metricdata.Sum[int64]).DataPoints {
+		reason, _ := dp.Attributes.Value(reasonKey)
+		syncTotal[reason.AsString()] = dp.Value
+	}
+	expectedSyncTotal := map[string]int64{
+		ConditionReasonCreateSuccessful:             1,
+		ConditionReasonSecretUpToDate:               1,
+		ConditionReasonRemoteSecretStoreFetchFailed: 1,
+	}
+	if !reflect.DeepEqual(syncTotal, expectedSyncTotal) {
+		t.Fatalf("expected secret_sync_total %v, got %v", expectedSyncTotal, syncTotal)
+	}
+
+	var syncCount uint64
+	for _, dp := range metrics["secret_sync_duration_seconds"].(metricdata.Histogram[float64]).DataPoints {
+		syncCount += dp.Count
+	}
+	if syncCount != 3 {
+		t.Fatalf("expected 3 secret_sync_duration_seconds observations, got %d", syncCount)
+	}
+
+	gauge := metrics["secret_sync_seconds_since_last_success"].(metricdata.Gauge[float64]).DataPoints
+	if len(gauge) != 1 || gauge[0].Value != 30 {
+		t.Fatalf("expected 30 seconds since the last successful sync, got %v", gauge)
+	}
+
+	// a deleted SecretSync is no longer reported
+	ss := getSecretSyncObject(t, ssc, req)
+	if err := ssc.Delete(context.Background(), ss); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _ = ssc.Reconcile(context.Background(), req)
+
+	metrics = collectMetrics(t, reader)
+	if gauge, ok := metrics["secret_sync_seconds_since_last_success"]; ok && len(gauge.(metricdata.Gauge[float64]).DataPoints) > 0 {
+		t.Fatalf("expected no seconds since last successful sync after deletion, got %v", gauge)
+	}
+}
+
+// collectMetrics returns the data of the metrics collected by the reader by name.
+func collectMetrics(t *testing.T, reader sdkmetric.Reader) map[string]metricdata.Aggregation {
+	t.Helper()
+
+	rm := metricdata.ResourceMetrics{}
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("unexpected error collecting metrics: %v", err)
+	}
+	metrics := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	return metrics
+}
+
+// drainEvents returns the events recorded so far.
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case e := <-recorder.Events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func getSecretSyncObject(t *testing.T, ssc *SecretSyncReconciler, req ctrl.Request) *secretsyncv1alpha1.SecretSync {
+	t.Helper()
+
+	secretSync := &secretsyncv1alpha1.SecretSync{}
+	err := ssc.Get(context.Background(), req.NamespacedName, secretSync)
+	if err != nil && !apierrors.IsNotFound(err) {
+		t.Fatalf("error getting secret sync: %v", err)
+	}
+
+	return secretSync
+}
+
+func newSecretSyncReconciler(
+	t *testing.T,
+	scheme *runtime.Scheme,
+	spc *secretsstorecsiv1.SecretProviderClass,
+	secretSync *secretsyncv1alpha1.SecretSync,
+	testSecret *corev1.Secret,
+) *testSecretSyncReconciler {
+	t.Helper()
+
+	initObjects := []client.Object{
+		testSecret,
+		spc,
+		secretSync,
+	}
+
+	kubeClient := fakeclient.NewClientset(testSecret)
+
+	// Create a fake client to mock API calls, the cached metadata of the
+	// managed secrets is read from the clientset
+	ctrlClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(initObjects...).
+		WithStatusSubresource(secretSync).
+		WithIndex(&secretsyncv1alpha1.SecretSync{}, secretProviderClassNameIndexKey, secretProviderClassNameIndexer).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				secretMeta, ok := obj.(*metav1.PartialObjectMetadata)
+				if !ok || secretMeta.GroupVersionKind() != corev1.SchemeGroupVersion.WithKind("Secret") {
+					return c.Get(ctx, key, obj, opts...)
+				}
+				secret, err := kubeClient.CoreV1().Secrets(key.Namespace).Get(ctx, key.Name, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				if !hasControllerLabel(secret) {
+					return apierrors.NewNotFound(corev1.Resource("secrets"), key.Name)
+				}
+				secretMeta.ObjectMeta = secret.ObjectMeta
+				return nil
+			},
+		}).
+		Build()
+
+	// Create a mock provider named "fake-provider".
+	// t.TempDir() creates a temporary directory which might have long path. sever.Start() fails with long path.
+	// So, create a temporary directory with shorter path.
+	socketPath, _ := os.MkdirTemp("/tmp", "e2e-secret-sync-controller-test-")
+	t.Cleanup(func() {
+		err := os.RemoveAll(socketPath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	server, err := providerfake.NewMocKCSIProviderServer(filepath.Join(socketPath, "fake-provider.sock"))
+	if err != nil {
+		t.Fatalf("unexpected mock provider failure: %v", err)
+	}
+
+	server.SetObjects(map[string]string{"secret/object1": "v1"})
+	server.SetFiles([]*v1alpha1.File{
+		{
+			Path:     "foo",
+			Mode:     0644,
+			Contents: []byte("foo"),
+		},
+	})
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("unexpected mock provider start failure: %v", err)
+	}
+	t.Cleanup(server.Stop)
+
+	providerClients := provider.NewPluginClientBuilder([]string{socketPath})
+
+	statsReporter, err := newStatsReporter(otel.Meter(scope))
+	if err != nil {
+		t.Fatalf("unexpected stats reporter failure: %v", err)
+	}
+
+	// Create a ReconcileSecretSync object with the scheme and fake client
+	// the fake clientset does not set the UIDs, the drift is detected from the
+	// UID of the applied secrets
+	kubeClient.PrependReactor("patch", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		handled, obj, err := clienttesting.ObjectReaction(kubeClient.Tracker())(action)
+		if !handled || err != nil {
+			return handled, obj, err
+		}
+		secret := obj.(*corev1.Secret)
+		if len(secret.UID) == 0 {
+			secret.UID = uuid.NewUUID()
+			err = kubeClient.Tracker().Update(corev1.SchemeGroupVersion.WithResource("secrets"), secret, secret.Namespace, metav1.UpdateOptions{})
+		}
+		return true, secret, err
+	})
+	kubeClient.PrependReactor("create", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if secret := action.(clienttesting.CreateAction).GetObject().(*corev1.Secret); len(secret.UID) == 0 {
+			secret.UID = uuid.NewUUID()
+		}
+		return false, nil, nil
+	})
 	ssc := &SecretSyncReconciler{
 		Client:          ctrlClient,
 		Clientset:       kubeClient,
 		Scheme:          scheme,
 		TokenCache:      token.NewManager(kubeClient),
 		ProviderClients: providerClients,
+		EventRecorder:   record.NewFakeRecorder(100),
+		statsReporter:   statsReporter,
 	}
 
 	return &testSecretSyncReconciler{
@@ -0,0 +1,189 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	scope = "sigs.k8s.io/secrets-store-sync-controller"
+
+	namespaceKey = "namespace"
+	nameKey      = "name"
+	driftTypeKey = "drift_type"
+	reasonKey    = "reason"
+	providerKey  = "provider"
+	resultKey    = "result"
+	stateKey     = "state"
+
+	resultHit  = "hit"
+	resultMiss = "miss"
+
+	stateScheduled = "scheduled"
+	stateDue       = "due"
+	stateThrottled = "throttled"
+)
+
+type reporter struct {
+	secretDriftTotal metric.Int64Counter
+	syncTotal        metric.Int64Counter
+	syncDuration     metric.Float64Histogram
+	mountCacheTotal  metric.Int64Counter
+
+	// lastSuccessfulSync holds the last successful sync time of every
+	// SecretSync, observed by the seconds since last successful sync gauge.
+	lock               sync.Mutex
+	lastSuccessfulSync map[types.NamespacedName]time.Time
+	now                func() time.Time
+
+	// rotationQueueDepth returns the depth of the rotation queue, observed by
+	// the rotation queue depth gauge.
+	rotationQueueDepth func() rotationQueueDepth
+}
+
+// newStatsReporter creates the instruments used by the SecretSync controller
+// with the given meter, usually the meter of the global meter provider which
+// is set up by the configured metrics backend.
+func newStatsReporter(meter metric.Meter) (*reporter, error) {
+	var err error
+
+	r := &reporter{
+		lastSuccessfulSync: make(map[types.NamespacedName]time.Time),
+		now:                time.Now,
+	}
+
+	if r.secretDriftTotal, err = meter.Int64Counter(
+		"secret_drift_total",
+		metric.WithDescription("Total number of managed secrets restored after being modified or deleted outside of the controller"),
+	); err != nil {
+		return nil, err
+	}
+	if r.syncTotal, err = meter.Int64Counter(
+		"secret_sync_total",
+		metric.WithDescription("Total number of SecretSync reconciliations by the resulting condition reason"),
+	); err != nil {
+		return nil, err
+	}
+	if r.syncDuration, err = meter.Float64Histogram(
+		"secret_sync_duration_seconds",
+		metric.WithDescription("Duration of SecretSync reconciliations by the resulting condition reason"),
+		metric.WithUnit("s"),
+	); err != nil {
+		return nil, err
+	}
+	if r.mountCacheTotal, err = meter.Int64Counter(
+		"mount_cache_requests_total",
+		metric.WithDescription("Total number of Mount response lookups by cache result, a hit saved a provider call"),
+	); err != nil {
+		return nil, err
+	}
+	if _, err = meter.Float64ObservableGauge(
+		"secret_sync_seconds_since_last_success",
+		metric.WithDescription("Seconds since the last successful sync of each SecretSync"),
+		metric.WithUnit("s"),
+		metric.WithFloat64Callback(r.observeLastSuccessfulSync),
+	); err != nil {
+		return nil, err
+	}
+	if _, err = meter.Int64ObservableGauge(
+		"rotation_queue_depth",
+		metric.WithDescription("Number of SecretSyncs waiting for their next sync time (scheduled), past their next sync time (due) or waiting for the provider calls rate limit (throttled)"),
+		metric.WithInt64Callback(r.observeRotationQueueDepth),
+	); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *reporter) reportSecretDrift(ctx context.Context, namespace, driftType string) {
+	opt := metric.WithAttributes(
+		attribute.Key(namespaceKey).String(namespace),
+		attribute.Key(driftTypeKey).String(driftType),
+	)
+	r.secretDriftTotal.Add(ctx, 1, opt)
+}
+
+func (r *reporter) reportSync(ctx context.Context, namespace, reason string, duration time.Duration) {
+	opt := metric.WithAttributes(
+		attribute.Key(namespaceKey).String(namespace),
+		attribute.Key(reasonKey).String(reason),
+	)
+	r.syncTotal.Add(ctx, 1, opt)
+	r.syncDuration.Record(ctx, duration.Seconds(), opt)
+}
+
+func (r *reporter) reportMountCacheRequest(ctx context.Context, provider string, hit bool) {
+	result := resultMiss
+	if hit {
+		result = resultHit
+	}
+	r.mountCacheTotal.Add(ctx, 1, metric.WithAttributes(
+		attribute.Key(providerKey).String(provider),
+		attribute.Key(resultKey).String(result),
+	))
+}
+
+// setLastSuccessfulSync records the last successful sync time of a SecretSync.
+func (r *reporter) setLastSuccessfulSync(key types.NamespacedName, t time.Time) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.lastSuccessfulSync[key] = t
+}
+
+// forgetSecretSync stops reporting the last successful sync time of a SecretSync.
+func (r *reporter) forgetSecretSync(key types.NamespacedName) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.lastSuccessfulSync, key)
+}
+
+func (r *reporter) observeLastSuccessfulSync(_ context.Context, o metric.Float64Observer) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := r.now()
+	for key, t := range r.lastSuccessfulSync {
+		o.Observe(now.Sub(t).Seconds(), metric.WithAttributes(
+			attribute.Key(namespaceKey).String(key.Namespace),
+			attribute.Key(nameKey).String(key.Name),
+		))
+	}
+	return nil
+}
+
+func (r *reporter) observeRotationQueueDepth(_ context.Context, o metric.Int64Observer) error {
+	if r.rotationQueueDepth == nil {
+		return nil
+	}
+
+	depth := r.rotationQueueDepth()
+	for state, value := range map[string]int{
+		stateScheduled: depth.scheduled,
+		stateDue:       depth.due,
+		stateThrottled: depth.throttled,
+	} {
+		o.Observe(int64(value), metric.WithAttributes(attribute.Key(stateKey).String(state)))
+	}
+	return nil
+}
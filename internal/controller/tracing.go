@@ -0,0 +1,46 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// startSpan starts a span of the SecretSync controller with the global tracer
+// provider, which is set up by the configured tracing backend.
+func startSpan(ctx context.Context, name string, key types.NamespacedName) (context.Context, trace.Span) {
+	return otel.Tracer(scope).Start(ctx, name, trace.WithAttributes(
+		attribute.String("secretsync.namespace", key.Namespace),
+		attribute.String("secretsync.name", key.Name),
+	))
+}
+
+// endSpan ends the span, marking it as failed if err is not nil. The errors
+// never contain secret data, they are also reported in the conditions.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -17,27 +17,61 @@ limitations under the License.
 package metrics
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"strings"
 
+	crprometheus "github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/sdk/metric"
 	"k8s.io/klog/v2"
 )
 
 var (
-	metricsBackend = flag.String("metrics-backend", "Prometheus", "Backend used for metrics")
+	metricsBackend = flag.String("metrics-backend", "Prometheus", "Backend used for metrics: Prometheus, otlp (OTLP over gRPC, also otlp-grpc) or otlp-http")
 )
 
-const prometheusExporter = "prometheus"
+const (
+	prometheusExporter = "prometheus"
+	otlpExporter       = "otlp"
+	otlpGRPCExporter   = "otlp-grpc"
+	otlpHTTPExporter   = "otlp-http"
+)
 
-func InitMetricsExporter() error {
+// InitMetricsExporter sets up the global meter provider with the configured
+// metrics backend. The returned function flushes the pending metrics and
+// stops the exporter.
+func InitMetricsExporter() (func(context.Context) error, error) {
 	mb := strings.ToLower(*metricsBackend)
 	klog.InfoS("initializing metrics backend", "backend", mb)
 	switch mb {
-	// Prometheus is the only supported exporter
 	case prometheusExporter:
 		return initPrometheusExporter()
+	case otlpExporter, otlpGRPCExporter:
+		return initOTLPExporter(otlpProtocolGRPC)
+	case otlpHTTPExporter:
+		return initOTLPExporter(otlpProtocolHTTP)
 	default:
-		return fmt.Errorf("unsupported metrics backend %v", *metricsBackend)
+		return nil, fmt.Errorf("unsupported metrics backend %v", *metricsBackend)
 	}
 }
+
+// newMeterProvider creates a meter provider exporting the instruments through
+// the reader. All the backends share the same views so they export the same
+// metrics.
+func newMeterProvider(reader metric.Reader, opts ...metric.Option) *metric.MeterProvider {
+	opts = append([]metric.Option{
+		metric.WithReader(reader),
+		metric.WithView(metric.NewView(
+			// only durations, histograms in other units set their own boundaries
+			metric.Instrument{Kind: metric.InstrumentKindHistogram, Unit: "s"},
+			metric.Stream{
+				Aggregation: metric.AggregationExplicitBucketHistogram{
+					// Use custom buckets to avoid the default buckets which are too small for our use case.
+					// Start 100ms with last bucket being [~4m, +Inf)
+					Boundaries: crprometheus.ExponentialBucketsRange(0.1, 2, 11),
+				}},
+		)),
+	}, opts...)
+	return metric.NewMeterProvider(opts...)
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"google.golang.org/grpc/credentials"
+)
+
+var (
+	otlpEndpoint       = flag.String("otlp-endpoint", "", "Address (host:port) of the OpenTelemetry collector the metrics are pushed to with the otlp metrics backends. Defaults to the OTEL_EXPORTER_OTLP_ENDPOINT environment variable, or the local collector.")
+	otlpInsecure       = flag.Bool("otlp-insecure", false, "Push the metrics to the OpenTelemetry collector without TLS.")
+	otlpCAFile         = flag.String("otlp-ca-file", "", "Path to the CA bundle used to verify the OpenTelemetry collector. Defaults to the system roots.")
+	otlpCertFile       = flag.String("otlp-cert-file", "", "Path to the client certificate presented to the OpenTelemetry collector.")
+	otlpKeyFile        = flag.String("otlp-key-file", "", "Path to the private key of the client certificate presented to the OpenTelemetry collector.")
+	otlpHeaders        = flag.String("otlp-headers", "", "Headers sent to the OpenTelemetry collector with every push, comma separated key=value pairs.")
+	otlpExportInterval = flag.Duration("otlp-export-interval", time.Minute, "Interval at which the metrics are pushed to the OpenTelemetry collector.")
+)
+
+type otlpProtocol string
+
+const (
+	otlpProtocolGRPC otlpProtocol = "grpc"
+	otlpProtocolHTTP otlpProtocol = "http"
+
+	serviceName = "secrets-store-sync-controller"
+)
+
+// otlpOptions configures the connection to the OpenTelemetry collector.
+type otlpOptions struct {
+	endpoint string
+	insecure bool
+	caFile   string
+	certFile string
+	keyFile  string
+	headers  map[string]string
+}
+
+func initOTLPExporter(protocol otlpProtocol) (func(context.Context) error, error) {
+	headers, err := parseOTLPHeaders(*otlpHeaders)
+	if err != nil {
+		return nil, err
+	}
+	if *otlpExportInterval <= 0 {
+		return nil, fmt.Errorf("invalid --otlp-export-interval %v, it must be positive", *otlpExportInterval)
+	}
+
+	exporter, err := newOTLPExporter(context.Background(), protocol, otlpOptions{
+		endpoint: *otlpEndpoint,
+		insecure: *otlpInsecure,
+		caFile:   *otlpCAFile,
+		certFile: *otlpCertFile,
+		keyFile:  *otlpKeyFile,
+		headers:  headers,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	meterProvider := newMeterProvider(
+		metric.NewPeriodicReader(exporter, metric.WithInterval(*otlpExportInterval)),
+		metric.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+
+	otel.SetMeterProvider(meterProvider)
+
+	return meterProvider.Shutdown, nil
+}
+
+// newOTLPExporter creates the exporter pushing the metrics to the OpenTelemetry
+// collector with the given protocol. Settings which are not set fall back to
+// the OTEL_EXPORTER_OTLP_* environment variables.
+func newOTLPExporter(ctx context.Context, protocol otlpProtocol, opts otlpOptions) (metric.Exporter, error) {
+	tlsConfig, err := opts.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	switch protocol {
+	case otlpProtocolGRPC:
+		var grpcOpts []otlpmetricgrpc.Option
+		if len(opts.endpoint) > 0 {
+			grpcOpts = append(grpcOpts, otlpmetricgrpc.WithEndpoint(opts.endpoint))
+		}
+		if opts.insecure {
+			grpcOpts = append(grpcOpts, otlpmetricgrpc.WithInsecure())
+		} else if tlsConfig != nil {
+			grpcOpts = append(grpcOpts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(tlsConfig)))
+		}
+		if len(opts.headers) > 0 {
+			grpcOpts = append(grpcOpts, otlpmetricgrpc.WithHeaders(opts.headers))
+		}
+		return otlpmetricgrpc.New(ctx, grpcOpts...)
+	case otlpProtocolHTTP:
+		var httpOpts []otlpmetrichttp.Option
+		if len(opts.endpoint) > 0 {
+			httpOpts = append(httpOpts, otlpmetrichttp.WithEndpoint(opts.endpoint))
+		}
+		if opts.insecure {
+			httpOpts = append(httpOpts, otlpmetrichttp.WithInsecure())
+		} else if tlsConfig != nil {
+			httpOpts = append(httpOpts, otlpmetrichttp.WithTLSClientConfig(tlsConfig))
+		}
+		if len(opts.headers) > 0 {
+			httpOpts = append(httpOpts, otlpmetrichttp.WithHeaders(opts.headers))
+		}
+		return otlpmetrichttp.New(ctx, httpOpts...)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %v", protocol)
+	}
+}
+
+// tlsConfig returns the TLS configuration to connect to the collector, or nil
+// if neither a CA bundle nor a client certificate is configured.
+func (o otlpOptions) tlsConfig() (*tls.Config, error) {
+	if o.insecure || (len(o.caFile) == 0 && len(o.certFile) == 0 && len(o.keyFile) == 0) {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(o.caFile) > 0 {
+		caPEM, err := os.ReadFile(o.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OTLP CA file: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in OTLP CA file %s", o.caFile)
+		}
+	}
+	if len(o.certFile) > 0 || len(o.keyFile) > 0 {
+		if len(o.certFile) == 0 || len(o.keyFile) == 0 {
+			return nil, fmt.Errorf("both the OTLP client certificate and key files must be set")
+		}
+		cert, err := tls.LoadX509KeyPair(o.certFile, o.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load OTLP client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// parseOTLPHeaders parses comma separated key=value pairs.
+func parseOTLPHeaders(value string) (map[string]string, error) {
+	headers := make(map[string]string)
+	if len(value) == 0 {
+		return headers, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || len(key) == 0 {
+			// the value may be a credential, never log it
+			return nil, fmt.Errorf("invalid --otlp-headers, expected comma separated key=value pairs")
+		}
+		headers[key] = strings.TrimSpace(val)
+	}
+	return headers, nil
+}
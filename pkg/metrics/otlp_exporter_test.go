@@ -0,0 +1,271 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/sdk/metric"
+	collectormetricsv1 "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+const testHeader = "x-test-header"
+
+// fakeCollector is an in-process OTLP receiver recording the names of the
+// pushed metrics and the value of the test header.
+type fakeCollector struct {
+	collectormetricsv1.UnimplementedMetricsServiceServer
+
+	lock    sync.Mutex
+	metrics []string
+	headers []string
+}
+
+func (c *fakeCollector) record(req *collectormetricsv1.ExportMetricsServiceRequest, headers []string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.headers = append(c.headers, headers...)
+	for _, rm := range req.GetResourceMetrics() {
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				c.metrics = append(c.metrics, m.GetName())
+			}
+		}
+	}
+}
+
+func (c *fakeCollector) Export(ctx context.Context, req *collectormetricsv1.ExportMetricsServiceRequest) (*collectormetricsv1.ExportMetricsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.record(req, md.Get(testHeader))
+	return &collectormetricsv1.ExportMetricsServiceResponse{}, nil
+}
+
+func (c *fakeCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := &collectormetricsv1.ExportMetricsServiceRequest{}
+	if err := proto.Unmarshal(body, req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.record(req, r.Header.Values(testHeader))
+
+	resp, _ := proto.Marshal(&collectormetricsv1.ExportMetricsServiceResponse{})
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(resp)
+}
+
+func startGRPCCollector(t *testing.T, collector *fakeCollector) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server := grpc.NewServer()
+	collectormetricsv1.RegisterMetricsServiceServer(server, collector)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+// startHTTPSCollector starts the collector with a self-signed certificate and
+// returns its address and the path to the CA bundle trusting it.
+func startHTTPSCollector(t *testing.T, collector *fakeCollector) (string, string) {
+	t.Helper()
+
+	server := httptest.NewTLSServer(collector)
+	t.Cleanup(server.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return strings.TrimPrefix(server.URL, "https://"), caFile
+}
+
+func TestOTLPExporter(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol otlpProtocol
+		options  func(t *testing.T, collector *fakeCollector) otlpOptions
+	}{
+		{
+			name:     "grpc",
+			protocol: otlpProtocolGRPC,
+			options: func(t *testing.T, collector *fakeCollector) otlpOptions {
+				return otlpOptions{
+					endpoint: startGRPCCollector(t, collector),
+					insecure: true,
+					headers:  map[string]string{testHeader: "value"},
+				}
+			},
+		},
+		{
+			name:     "http with tls",
+			protocol: otlpProtocolHTTP,
+			options: func(t *testing.T, collector *fakeCollector) otlpOptions {
+				endpoint, caFile := startHTTPSCollector(t, collector)
+				return otlpOptions{
+					endpoint: endpoint,
+					caFile:   caFile,
+					headers:  map[string]string{testHeader: "value"},
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			collector := &fakeCollector{}
+
+			exporter, err := newOTLPExporter(ctx, test.protocol, test.options(t, collector))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			meterProvider := newMeterProvider(metric.NewPeriodicReader(exporter, metric.WithInterval(time.Hour)))
+			t.Cleanup(func() {
+				_ = meterProvider.Shutdown(ctx)
+			})
+
+			meter := meterProvider.Meter("test")
+			counter, err := meter.Int64Counter("test_total")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			histogram, err := meter.Float64Histogram("test_duration_seconds")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			counter.Add(ctx, 1)
+			histogram.Record(ctx, 0.5)
+
+			if err := meterProvider.ForceFlush(ctx); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			collector.lock.Lock()
+			defer collector.lock.Unlock()
+			assert.ElementsMatch(t, []string{"test_total", "test_duration_seconds"}, collector.metrics)
+			assert.Equal(t, []string{"value"}, collector.headers)
+		})
+	}
+}
+
+func TestOTLPTLSConfig(t *testing.T) {
+	tests := []struct {
+		name                string
+		options             otlpOptions
+		expectedErrorString string
+	}{
+		{
+			name:    "no tls settings",
+			options: otlpOptions{},
+		},
+		{
+			name:    "insecure",
+			options: otlpOptions{insecure: true, caFile: "ca.crt"},
+		},
+		{
+			name:                "missing ca file",
+			options:             otlpOptions{caFile: filepath.Join(t.TempDir(), "ca.crt")},
+			expectedErrorString: "failed to read OTLP CA file",
+		},
+		{
+			name:                "client certificate without key",
+			options:             otlpOptions{certFile: "tls.crt"},
+			expectedErrorString: "both the OTLP client certificate and key files must be set",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tlsConfig, err := test.options.tlsConfig()
+			if len(test.expectedErrorString) > 0 {
+				assert.ErrorContains(t, err, test.expectedErrorString)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Nil(t, tlsConfig)
+		})
+	}
+}
+
+func TestParseOTLPHeaders(t *testing.T) {
+	tests := []struct {
+		name                string
+		value               string
+		expected            map[string]string
+		expectedErrorString string
+	}{
+		{
+			name:     "empty",
+			value:    "",
+			expected: map[string]string{},
+		},
+		{
+			name:     "several headers",
+			value:    "authorization=Bearer token, x-tenant = a=b",
+			expected: map[string]string{"authorization": "Bearer token", "x-tenant": "a=b"},
+		},
+		{
+			name:                "missing value",
+			value:               "authorization",
+			expectedErrorString: "invalid --otlp-headers, expected comma separated key=value pairs",
+		},
+		{
+			name:                "missing key",
+			value:               "=secret",
+			expectedErrorString: "invalid --otlp-headers, expected comma separated key=value pairs",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := parseOTLPHeaders(test.value)
+			if len(test.expectedErrorString) > 0 {
+				assert.EqualError(t, err, test.expectedErrorString)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
@@ -17,35 +17,25 @@ limitations under the License.
 package metrics
 
 import (
+	"context"
+
 	crprometheus "github.com/prometheus/client_golang/prometheus"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/prometheus"
-	"go.opentelemetry.io/otel/sdk/metric"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
-func initPrometheusExporter() error {
+func initPrometheusExporter() (func(context.Context) error, error) {
 	exporter, err := prometheus.New(
 		prometheus.WithRegisterer(metrics.Registry.(*crprometheus.Registry)), // using the controller-runtime prometheus metrics registry
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	meterProvider := metric.NewMeterProvider(
-		metric.WithReader(exporter),
-		metric.WithView(metric.NewView(
-			metric.Instrument{Kind: metric.InstrumentKindHistogram},
-			metric.Stream{
-				Aggregation: metric.AggregationExplicitBucketHistogram{
-					// Use custom buckets to avoid the default buckets which are too small for our use case.
-					// Start 100ms with last bucket being [~4m, +Inf)
-					Boundaries: crprometheus.ExponentialBucketsRange(0.1, 2, 11),
-				}},
-		)),
-	)
+	meterProvider := newMeterProvider(exporter)
 
 	otel.SetMeterProvider(meterProvider)
 
-	return nil
+	return meterProvider.Shutdown, nil
 }
@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ParseAliases parses provider aliases in the alias=provider format into a
+// map from the alias to the name of the provider socket.
+func ParseAliases(values []string) (map[string]string, error) {
+	aliases := make(map[string]string, len(values))
+	for _, value := range values {
+		alias, provider, ok := strings.Cut(value, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid provider alias %q, expected alias=provider", value)
+		}
+		if !pluginNameRe.MatchString(alias) || len(alias) == 0 {
+			return nil, fmt.Errorf("%w: alias %q", errInvalidProvider, alias)
+		}
+		if !pluginNameRe.MatchString(provider) || len(provider) == 0 {
+			return nil, fmt.Errorf("%w: provider %q", errInvalidProvider, provider)
+		}
+		if previous, ok := aliases[alias]; ok && previous != provider {
+			return nil, fmt.Errorf("provider alias %q is set to both %q and %q", alias, previous, provider)
+		}
+		aliases[alias] = provider
+	}
+	// aliases are resolved once, an alias of an alias would not be followed
+	for alias, provider := range aliases {
+		if _, ok := aliases[provider]; ok && alias != provider {
+			return nil, fmt.Errorf("provider alias %q refers to the alias %q", alias, provider)
+		}
+	}
+	return aliases, nil
+}
+
+// SetAliases sets the aliases of the providers, so the clients of a provider
+// requested by its alias connect to the socket of the aliased provider. It
+// must be called before the plugin client builder is used.
+func (p *PluginClientBuilder) SetAliases(aliases map[string]string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.aliases = maps.Clone(aliases)
+}
+
+// resolve returns the name of the provider socket of the provider, which is
+// the provider itself unless it is an alias.
+func (p *PluginClientBuilder) resolve(provider string) string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if resolved, ok := p.aliases[provider]; ok {
+		return resolved
+	}
+	return provider
+}
+
+// ValidateSockets returns an error if the socket of a provider exists in more
+// than one provider volume path, or if the socket of an alias or of a remote
+// provider exists, as the provider it resolves to would be ambiguous.
+func (p *PluginClientBuilder) ValidateSockets() error {
+	found := map[string][]string{}
+	for _, path := range p.socketPaths {
+		entries, err := os.ReadDir(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list provider volume %s: %w", path, err)
+		}
+		for _, entry := range entries {
+			provider, ok := strings.CutSuffix(entry.Name(), socketSuffix)
+			if ok && pluginNameRe.MatchString(provider) {
+				found[provider] = append(found[provider], filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	p.lock.RLock()
+	aliases := maps.Clone(p.aliases)
+	remotes := maps.Clone(p.remotes)
+	p.lock.RUnlock()
+
+	var errs []error
+	for _, provider := range slices.Sorted(maps.Keys(found)) {
+		if sockets := found[provider]; len(sockets) > 1 {
+			errs = append(errs, fmt.Errorf("conflicting sockets for provider %q: %s", provider, strings.Join(sockets, ", ")))
+		}
+		if resolved, ok := aliases[provider]; ok && resolved != provider {
+			errs = append(errs, fmt.Errorf("socket %s conflicts with the alias of provider %q", found[provider][0], resolved))
+		}
+		if remote, ok := remotes[provider]; ok {
+			errs = append(errs, fmt.Errorf("socket %s conflicts with the remote provider %q at %s", found[provider][0], provider, remote.Target))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAliases(t *testing.T) {
+	tests := []struct {
+		name          string
+		values        []string
+		expected      map[string]string
+		expectedError string
+	}{
+		{
+			name:     "no aliases",
+			expected: map[string]string{},
+		},
+		{
+			name:     "aliases",
+			values:   []string{"vault=hashicorp-vault", "aws=provider-aws", "vault=hashicorp-vault"},
+			expected: map[string]string{"vault": "hashicorp-vault", "aws": "provider-aws"},
+		},
+		{
+			name:          "missing provider",
+			values:        []string{"vault"},
+			expectedError: `invalid provider alias "vault", expected alias=provider`,
+		},
+		{
+			name:          "invalid alias",
+			values:        []string{"=hashicorp-vault"},
+			expectedError: `invalid provider: alias ""`,
+		},
+		{
+			name:          "invalid provider",
+			values:        []string{"vault=../vault"},
+			expectedError: `invalid provider: provider "../vault"`,
+		},
+		{
+			name:          "conflicting aliases",
+			values:        []string{"vault=hashicorp-vault", "vault=openbao"},
+			expectedError: `provider alias "vault" is set to both "hashicorp-vault" and "openbao"`,
+		},
+		{
+			name:          "alias of an alias",
+			values:        []string{"vault=hashicorp-vault", "hashicorp-vault=openbao"},
+			expectedError: `provider alias "vault" refers to the alias "hashicorp-vault"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aliases, err := ParseAliases(tt.values)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, aliases)
+		})
+	}
+}
+
+func TestGetAlias(t *testing.T) {
+	pcb := NewPluginClientBuilder([]string{"/nonexistent", newFakeProvider(t, "hashicorp-vault")})
+	t.Cleanup(pcb.Cleanup)
+	pcb.SetAliases(map[string]string{"vault": "hashicorp-vault"})
+
+	client, err := pcb.Get(context.Background(), "vault")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	aliased, err := pcb.Get(context.Background(), "hashicorp-vault")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Same(t, aliased, client, "expected the alias to share the client of the provider")
+	assert.Len(t, pcb.clients, 1)
+
+	pcb.checkProviders(context.Background(), nil)
+	assert.NoError(t, pcb.Checker([]string{"vault"})(nil))
+}
+
+func TestValidateSockets(t *testing.T) {
+	first := newFakeProvider(t, "provider-a")
+	second := newFakeProvider(t, "provider-b")
+	conflicting := newFakeProvider(t, "provider-a")
+
+	pcb := NewPluginClientBuilder([]string{first, second, "/nonexistent"})
+	assert.NoError(t, pcb.ValidateSockets())
+
+	pcb.SetAliases(map[string]string{"provider-b": "provider-c"})
+	assert.EqualError(t, pcb.ValidateSockets(), fmt.Sprintf(`socket %s conflicts with the alias of provider "provider-c"`, filepath.Join(second, "provider-b.sock")))
+
+	pcb = NewPluginClientBuilder([]string{first, second, conflicting})
+	assert.EqualError(t, pcb.ValidateSockets(), fmt.Sprintf(`conflicting sockets for provider "provider-a": %s, %s`, filepath.Join(first, "provider-a.sock"), filepath.Join(conflicting, "provider-a.sock")))
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/secrets-store-csi-driver/provider/v1alpha1"
+)
+
+// healthCheckTimeout bounds the Version() RPC of a single provider healthcheck.
+const healthCheckTimeout = 5 * time.Second
+
+// providerHealth is the result of the last healthcheck of a provider.
+type providerHealth struct {
+	healthy        bool
+	runtimeVersion string
+	lastChecked    time.Time
+}
+
+// HealthCheck enables periodic healthcheck for configured provider clients by making
+// a Version() RPC call. The required providers are always checked, even if
+// no client was requested for them yet. The results are reported by the
+// provider_up and provider_info metrics and by Checker.
+//
+// This method blocks until the parent context is canceled during termination.
+func (p *PluginClientBuilder) HealthCheck(ctx context.Context, interval time.Duration, requiredProviders []string) {
+	unregister, err := getStatsReporter().registerHealthCallback(p)
+	if err != nil {
+		klog.ErrorS(err, "failed to register provider health metrics")
+	} else {
+		defer unregister()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.checkProviders(ctx, requiredProviders)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkProviders checks the health of the providers with a client and of the
+// required providers.
+func (p *PluginClientBuilder) checkProviders(ctx context.Context, requiredProviders []string) {
+	for _, provider := range requiredProviders {
+		if _, err := p.Get(ctx, provider); err != nil {
+			p.setHealth(p.resolve(provider), providerHealth{lastChecked: time.Now()}, err)
+		}
+	}
+
+	p.lock.RLock()
+	clients := maps.Clone(p.clients)
+	p.lock.RUnlock()
+
+	for provider, client := range clients {
+		p.checkProvider(ctx, provider, client)
+	}
+}
+
+func (p *PluginClientBuilder) checkProvider(ctx context.Context, provider string, client v1alpha1.CSIDriverProviderClient) {
+	c, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	runtimeVersion, err := Version(c, client)
+	p.setHealth(provider, providerHealth{
+		healthy:        err == nil,
+		runtimeVersion: runtimeVersion,
+		lastChecked:    time.Now(),
+	}, err)
+}
+
+// setHealth records the result of a healthcheck, logging the changes of the
+// provider health.
+func (p *PluginClientBuilder) setHealth(provider string, health providerHealth, err error) {
+	p.healthLock.Lock()
+	previous, checked := p.health[provider]
+	p.health[provider] = health
+	p.healthLock.Unlock()
+
+	switch {
+	case !health.healthy && (!checked || previous.healthy):
+		klog.ErrorS(err, "provider healthcheck failed", "provider", provider)
+	case health.healthy && (!checked || !previous.healthy || previous.runtimeVersion != health.runtimeVersion):
+		klog.InfoS("provider healthcheck successful", "provider", provider, "runtimeVersion", health.runtimeVersion)
+	default:
+		klog.V(5).InfoS("provider healthcheck done", "provider", provider, "healthy", health.healthy, "runtimeVersion", health.runtimeVersion)
+	}
+}
+
+// providersHealth returns a copy of the last healthcheck results by provider.
+func (p *PluginClientBuilder) providersHealth() map[string]providerHealth {
+	p.healthLock.RLock()
+	defer p.healthLock.RUnlock()
+	return maps.Clone(p.health)
+}
+
+// Checker returns a readiness checker which fails until the last healthcheck
+// of every required provider succeeded.
+func (p *PluginClientBuilder) Checker(requiredProviders []string) func(*http.Request) error {
+	return func(_ *http.Request) error {
+		health := p.providersHealth()
+
+		var unhealthy []string
+		for _, provider := range requiredProviders {
+			if !health[p.resolve(provider)].healthy {
+				unhealthy = append(unhealthy, provider)
+			}
+		}
+		if len(unhealthy) > 0 {
+			slices.Sort(unhealthy)
+			return fmt.Errorf("required providers are not healthy: %v", unhealthy)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	providerfake "sigs.k8s.io/secrets-store-csi-driver/provider/fake"
+)
+
+func newFakeProvider(t *testing.T, name string) string {
+	t.Helper()
+
+	// t.TempDir() might be too long for a unix socket path
+	socketPath, err := os.MkdirTemp("/tmp", "provider-health-test-")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(socketPath)
+	})
+
+	server, err := providerfake.NewMocKCSIProviderServer(filepath.Join(socketPath, name+".sock"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(server.Stop)
+	return socketPath
+}
+
+func TestHealthCheck(t *testing.T) {
+	pcb := NewPluginClientBuilder([]string{newFakeProvider(t, "fake-provider")})
+	t.Cleanup(pcb.Cleanup)
+
+	reader := sdkmetric.NewManualReader()
+	r, err := newStatsReporter(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter(scope))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	unregister, err := r.registerHealthCallback(pcb)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(unregister)
+
+	checker := pcb.Checker([]string{"fake-provider"})
+	assert.EqualError(t, checker(nil), "required providers are not healthy: [fake-provider]")
+	assert.NoError(t, pcb.Checker(nil)(nil))
+
+	pcb.checkProviders(context.Background(), []string{"fake-provider", "missing-provider"})
+
+	assert.NoError(t, checker(nil))
+	assert.EqualError(t, pcb.Checker([]string{"missing-provider", "fake-provider"})(nil), "required providers are not healthy: [missing-provider]")
+
+	rm := metricdata.ResourceMetrics{}
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	up := map[string]int64{}
+	versions := map[string]string{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		for _, dp := range m.Data.(metricdata.Gauge[int64]).DataPoints {
+			provider, _ := dp.Attributes.Value(providerKey)
+			switch m.Name {
+			case "provider_up":
+				up[provider.AsString()] = dp.Value
+			case "provider_info":
+				version, _ := dp.Attributes.Value(runtimeVersionKey)
+				versions[provider.AsString()] = version.AsString()
+			}
+		}
+	}
+	assert.Equal(t, map[string]int64{"fake-provider": 1, "missing-provider": 0}, up)
+	assert.Equal(t, map[string]string{"fake-provider": "0.0.10"}, versions)
+}
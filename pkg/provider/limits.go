@@ -0,0 +1,304 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+// mountMethod is the full name of the Mount RPC guarded by the mount limits.
+const mountMethod = "/v1alpha1.CSIDriverProvider/Mount"
+
+// ErrProviderUnavailable is returned by the Mount calls to a provider while
+// its circuit breaker is open.
+var ErrProviderUnavailable = errors.New("provider unavailable")
+
+// MountLimits bounds the Mount calls to the providers.
+type MountLimits struct {
+	// Timeout is the deadline of a Mount call, including the wait for an
+	// in-flight slot. Timeouts overrides it by provider. Zero disables it.
+	Timeout  time.Duration
+	Timeouts map[string]time.Duration
+	// MaxInFlight is the maximum number of concurrent Mount calls to a
+	// provider. MaxInFlightByProvider overrides it by provider. Zero
+	// disables the limit.
+	MaxInFlight           int
+	MaxInFlightByProvider map[string]int
+	// FailureThreshold is the number of consecutive failed Mount calls to a
+	// provider which opens its circuit breaker. Zero disables the breaker.
+	FailureThreshold int
+	// OpenDuration is the time an open circuit breaker fails the Mount calls
+	// fast, before letting a single trial call through.
+	OpenDuration time.Duration
+}
+
+// ParseOverrides parses values in either the value or the provider=value
+// format, as the default value and the values by provider. The default
+// value is unchanged unless a value without a provider is set.
+func ParseOverrides[T any](values []string, parse func(string) (T, error), defaultValue T) (T, map[string]T, error) {
+	overrides := map[string]T{}
+	for _, value := range values {
+		provider, raw, ok := strings.Cut(value, "=")
+		if !ok {
+			raw = provider
+		}
+		parsed, err := parse(raw)
+		if err != nil {
+			return defaultValue, nil, fmt.Errorf("invalid value %q: %w", value, err)
+		}
+		if !ok {
+			defaultValue = parsed
+			continue
+		}
+		if !pluginNameRe.MatchString(provider) || len(provider) == 0 {
+			return defaultValue, nil, fmt.Errorf("%w: provider %q", errInvalidProvider, provider)
+		}
+		overrides[provider] = parsed
+	}
+	return defaultValue, overrides, nil
+}
+
+// ParseMaxInFlight parses a maximum number of in-flight calls.
+func ParseMaxInFlight(value string) (int, error) {
+	maxInFlight, err := strconv.Atoi(value)
+	if err == nil && maxInFlight < 0 {
+		err = errors.New("must not be negative")
+	}
+	return maxInFlight, err
+}
+
+// SetMountLimits sets the limits of the Mount calls to the providers. It must
+// be called before the plugin client builder is used.
+func (p *PluginClientBuilder) SetMountLimits(limits MountLimits) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.limits = limits
+}
+
+// mountGuardFor returns the guard of the Mount calls to the provider. A single
+// guard is kept by provider, the circuit breaker stays open when the provider
+// is dialed again after its client was evicted.
+func (p *PluginClientBuilder) mountGuardFor(provider string) *mountGuard {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if g, ok := p.guards[provider]; ok {
+		return g
+	}
+	g := &mountGuard{
+		provider:         provider,
+		timeout:          p.limits.Timeout,
+		failureThreshold: p.limits.FailureThreshold,
+		openDuration:     p.limits.OpenDuration,
+		now:              time.Now,
+	}
+	if timeout, ok := p.limits.Timeouts[provider]; ok {
+		g.timeout = timeout
+	}
+	maxInFlight := p.limits.MaxInFlight
+	if m, ok := p.limits.MaxInFlightByProvider[provider]; ok {
+		maxInFlight = m
+	}
+	if maxInFlight > 0 {
+		g.inFlight = make(chan struct{}, maxInFlight)
+	}
+	p.guards[provider] = g
+	return g
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// mountGuard applies the timeout, the in-flight limit and the circuit breaker
+// of the Mount calls to a provider.
+type mountGuard struct {
+	provider         string
+	timeout          time.Duration
+	inFlight         chan struct{}
+	failureThreshold int
+	openDuration     time.Duration
+	now              func() time.Time
+
+	// lock guards the circuit breaker state
+	lock     sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// unaryClientInterceptor guards the Mount calls, the other RPCs are invoked
+// unchanged.
+func (g *mountGuard) unaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if method != mountMethod {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	trial, err := g.allow()
+	if err != nil {
+		return err
+	}
+
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+
+	if g.inFlight != nil {
+		select {
+		case g.inFlight <- struct{}{}:
+			defer func() { <-g.inFlight }()
+		case <-ctx.Done():
+			g.release(trial)
+			return status.Errorf(codes.ResourceExhausted, "too many in-flight Mount calls to provider %q: %v", g.provider, ctx.Err())
+		}
+	}
+
+	err = invoker(ctx, method, req, reply, cc, opts...)
+	g.done(trial, err)
+	return err
+}
+
+// allow returns an error if the circuit breaker is open. Once the open
+// duration elapsed, a single trial call is allowed, which closes the breaker
+// if it succeeds.
+func (g *mountGuard) allow() (trial bool, err error) {
+	if g.failureThreshold <= 0 {
+		return false, nil
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	switch g.state {
+	case breakerOpen:
+		if remaining := g.openDuration - g.now().Sub(g.openedAt); remaining > 0 {
+			return false, &unavailableError{provider: g.provider, retryAfter: remaining}
+		}
+		klog.InfoS("provider circuit breaker half-open, trying a Mount call", "provider", g.provider)
+		g.state = breakerHalfOpen
+		g.trial = true
+		return true, nil
+	case breakerHalfOpen:
+		if g.trial {
+			return false, &unavailableError{provider: g.provider}
+		}
+		g.trial = true
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// release gives the trial call back without a result.
+func (g *mountGuard) release(trial bool) {
+	if !trial {
+		return
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.trial = false
+}
+
+// done records the result of a Mount call in the circuit breaker.
+func (g *mountGuard) done(trial bool, err error) {
+	if g.failureThreshold <= 0 {
+		return
+	}
+	failed := isProviderFailure(err)
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	switch {
+	case trial:
+		g.trial = false
+		if failed {
+			g.open(err)
+		} else {
+			klog.InfoS("provider circuit breaker closed", "provider", g.provider)
+			g.state = breakerClosed
+			g.failures = 0
+		}
+	case g.state != breakerClosed:
+		// the call started before the breaker opened
+	case err == nil:
+		g.failures = 0
+	case failed:
+		g.failures++
+		if g.failures >= g.failureThreshold {
+			g.open(err)
+		}
+	}
+}
+
+func (g *mountGuard) open(err error) {
+	klog.ErrorS(err, "provider circuit breaker opened", "provider", g.provider, "failures", g.failures, "openDuration", g.openDuration)
+	g.state = breakerOpen
+	g.openedAt = g.now()
+}
+
+// isProviderFailure returns true if the error shows that the provider is
+// unavailable or overloaded, as opposed to the failures of a single request,
+// e.g. invalid parameters, which do not open the circuit breaker.
+func isProviderFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Aborted:
+		return !isMaxRecvMsgSizeError(err)
+	default:
+		return false
+	}
+}
+
+// unavailableError is the error of a Mount call failed fast by an open
+// circuit breaker. It is reported with the Unavailable gRPC code.
+type unavailableError struct {
+	provider   string
+	retryAfter time.Duration
+}
+
+func (e *unavailableError) Error() string {
+	if e.retryAfter > 0 {
+		return fmt.Sprintf("%v: circuit breaker of provider %q is open, retrying in %s", ErrProviderUnavailable, e.provider, e.retryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("%v: circuit breaker of provider %q is half-open, waiting for a trial call", ErrProviderUnavailable, e.provider)
+}
+
+func (e *unavailableError) Is(target error) bool {
+	return target == ErrProviderUnavailable
+}
+
+func (e *unavailableError) GRPCStatus() *status.Status {
+	return status.New(codes.Unavailable, e.Error())
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseOverrides(t *testing.T) {
+	timeout, timeouts, err := ParseOverrides([]string{"vault=10s", "2m", "aws=1m"}, time.ParseDuration, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, timeout)
+	assert.Equal(t, map[string]time.Duration{"vault": 10 * time.Second, "aws": time.Minute}, timeouts)
+
+	maxInFlight, maxInFlightByProvider, err := ParseOverrides([]string{"vault=4"}, ParseMaxInFlight, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, maxInFlight)
+	assert.Equal(t, map[string]int{"vault": 4}, maxInFlightByProvider)
+
+	_, _, err = ParseOverrides([]string{"vault=-1"}, ParseMaxInFlight, 0)
+	assert.EqualError(t, err, `invalid value "vault=-1": must not be negative`)
+	_, _, err = ParseOverrides([]string{"../vault=1s"}, time.ParseDuration, time.Minute)
+	assert.EqualError(t, err, `invalid provider: provider "../vault"`)
+}
+
+func TestMountGuardCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	g := &mountGuard{
+		provider:         "fake-provider",
+		failureThreshold: 2,
+		openDuration:     time.Minute,
+		now:              func() time.Time { return now },
+	}
+
+	var invoked int
+	mountErr := status.Error(codes.Unavailable, "connection refused")
+	mount := func() error {
+		return g.unaryClientInterceptor(context.Background(), mountMethod, nil, nil, nil, func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+			invoked++
+			return mountErr
+		})
+	}
+
+	// an invalid request does not count as a provider failure
+	mountErr = status.Error(codes.InvalidArgument, "invalid parameters")
+	assert.Error(t, mount())
+	mountErr = status.Error(codes.Unavailable, "connection refused")
+	assert.Error(t, mount())
+	assert.False(t, errors.Is(mount(), ErrProviderUnavailable), "expected the breaker to open after the failure")
+	assert.Equal(t, 3, invoked)
+
+	// the breaker is open
+	err := mount()
+	assert.ErrorIs(t, err, ErrProviderUnavailable)
+	assert.EqualError(t, err, `provider unavailable: circuit breaker of provider "fake-provider" is open, retrying in 1m0s`)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 3, invoked)
+
+	// the failed trial call opens the breaker again
+	now = now.Add(time.Minute)
+	assert.False(t, errors.Is(mount(), ErrProviderUnavailable))
+	assert.Equal(t, 4, invoked)
+	assert.ErrorIs(t, mount(), ErrProviderUnavailable)
+
+	// the successful trial call closes the breaker
+	now = now.Add(time.Minute)
+	mountErr = nil
+	assert.NoError(t, mount())
+	mountErr = status.Error(codes.Unavailable, "connection refused")
+	assert.False(t, errors.Is(mount(), ErrProviderUnavailable))
+	assert.Equal(t, 6, invoked)
+
+	// the other RPCs are not guarded
+	assert.False(t, errors.Is(mount(), ErrProviderUnavailable))
+	assert.ErrorIs(t, mount(), ErrProviderUnavailable)
+	assert.NoError(t, g.unaryClientInterceptor(context.Background(), "/v1alpha1.CSIDriverProvider/Version", nil, nil, nil, func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		return nil
+	}))
+}
+
+func TestMountGuardHalfOpenAllowsSingleTrial(t *testing.T) {
+	now := time.Now()
+	g := &mountGuard{
+		provider:         "fake-provider",
+		failureThreshold: 1,
+		openDuration:     time.Minute,
+		now:              func() time.Time { return now },
+	}
+	g.done(false, status.Error(codes.Unavailable, "connection refused"))
+
+	now = now.Add(time.Minute)
+	trial, err := g.allow()
+	assert.True(t, trial)
+	assert.NoError(t, err)
+
+	_, err = g.allow()
+	assert.EqualError(t, err, `provider unavailable: circuit breaker of provider "fake-provider" is half-open, waiting for a trial call`)
+
+	// a call which started before the breaker opened does not close it
+	g.done(false, nil)
+	_, err = g.allow()
+	assert.ErrorIs(t, err, ErrProviderUnavailable)
+
+	g.done(true, nil)
+	trial, err = g.allow()
+	assert.False(t, trial)
+	assert.NoError(t, err)
+}
+
+func TestMountGuardLimits(t *testing.T) {
+	pcb := NewPluginClientBuilder(nil)
+	pcb.SetMountLimits(MountLimits{
+		Timeout:               time.Minute,
+		Timeouts:              map[string]time.Duration{"slow-provider": 50 * time.Millisecond},
+		MaxInFlight:           2,
+		MaxInFlightByProvider: map[string]int{"slow-provider": 1},
+	})
+	assert.Equal(t, time.Minute, pcb.mountGuardFor("fake-provider").timeout)
+	assert.Equal(t, 2, cap(pcb.mountGuardFor("fake-provider").inFlight))
+	assert.Same(t, pcb.mountGuardFor("fake-provider"), pcb.mountGuardFor("fake-provider"))
+
+	g := pcb.mountGuardFor("slow-provider")
+	started, release := make(chan struct{}), make(chan struct{})
+	hang := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		close(started)
+		<-ctx.Done()
+		<-release
+		return status.FromContextError(ctx.Err()).Err()
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- g.unaryClientInterceptor(context.Background(), mountMethod, nil, nil, nil, hang)
+	}()
+	<-started
+
+	// the only in-flight slot is taken by the hung call
+	err := g.unaryClientInterceptor(context.Background(), mountMethod, nil, nil, nil, func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		t.Error("unexpected call while the in-flight slot is taken")
+		return nil
+	})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// the hung call times out
+	close(release)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(<-done))
+}
+
+func TestMountGuardKeptOnRedial(t *testing.T) {
+	pcb := NewPluginClientBuilder([]string{newFakeProvider(t, "fake-provider")})
+	t.Cleanup(pcb.Cleanup)
+	pcb.SetMountLimits(MountLimits{
+		FailureThreshold: 1,
+		OpenDuration:     time.Hour,
+	})
+
+	_, err := pcb.Get(context.Background(), "fake-provider")
+	assert.NoError(t, err)
+
+	// the breaker opens, then the socket event evicts the client
+	pcb.mountGuardFor("fake-provider").done(false, status.Error(codes.Unavailable, "connection refused"))
+	pcb.evict("fake-provider")
+
+	// the provider is dialed again, its breaker is still open
+	client, err := pcb.Get(context.Background(), "fake-provider")
+	assert.NoError(t, err)
+	_, _, err = MountContent(context.Background(), client, "fake-provider", "{}", "{}", nil)
+	assert.ErrorIs(t, err, ErrProviderUnavailable)
+}
@@ -24,10 +24,15 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
@@ -67,8 +72,11 @@ var (
 	pluginNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{0,30}$`)
 
 	errInvalidProvider       = errors.New("invalid provider")
-	errProviderNotFound      = errors.New("provider not found")
 	errMissingObjectVersions = errors.New("missing object versions")
+
+	// ErrProviderNotFound is returned by Get if the socket of the provider does
+	// not exist in any provider volume path.
+	ErrProviderNotFound = errors.New("provider not found")
 )
 
 // PluginClientBuilder builds and stores grpc clients for communicating with
@@ -79,6 +87,19 @@ type PluginClientBuilder struct {
 	socketPaths []string
 	lock        sync.RWMutex
 	opts        []grpc.DialOption
+	aliases     map[string]string
+	remotes     map[string]RemoteProvider
+	limits      MountLimits
+	guards      map[string]*mountGuard
+
+	// healthLock guards health
+	healthLock sync.RWMutex
+	health     map[string]providerHealth
+
+	// providersLock guards providers and listeners
+	providersLock sync.RWMutex
+	providers     []string
+	listeners     []ProvidersListener
 }
 
 // NewPluginClientBuilder creates a PluginClientBuilder that will connect to
@@ -87,7 +108,8 @@ type PluginClientBuilder struct {
 //
 //	<path>/<plugin_name>.sock
 //
-// where <plugin_name> must match the PluginNameRe regular expression.
+// where <plugin_name> must match the PluginNameRe regular expression, unless
+// the plugin is a remote provider set with SetRemoteProviders.
 //
 // Additional grpc dial options can also be set through opts and will be used
 // when creating all clients.
@@ -95,11 +117,13 @@ func NewPluginClientBuilder(paths []string, opts ...grpc.DialOption) *PluginClie
 	pcb := &PluginClientBuilder{
 		clients:     make(map[string]v1alpha1.CSIDriverProviderClient),
 		conns:       make(map[string]*grpc.ClientConn),
+		guards:      make(map[string]*mountGuard),
 		socketPaths: paths,
 		lock:        sync.RWMutex{},
+		health:      make(map[string]providerHealth),
 		opts: append(opts, []grpc.DialOption{
-			grpc.WithTransportCredentials(insecure.NewCredentials()), // the interface is only secured through filesystem ACLs
 			grpc.WithDefaultServiceConfig(ServiceConfig),
+			grpc.WithChainUnaryInterceptor(tracingUnaryClientInterceptor),
 		}...,
 		),
 	}
@@ -108,8 +132,10 @@ func NewPluginClientBuilder(paths []string, opts ...grpc.DialOption) *PluginClie
 
 // Get returns a CSIDriverProviderClient for the provider. If an existing client
 // is not found a new one will be created and added to the PluginClientBuilder.
+// An alias of a provider shares the client of the aliased provider.
 func (p *PluginClientBuilder) Get(_ context.Context, provider string) (v1alpha1.CSIDriverProviderClient, error) {
 	var out v1alpha1.CSIDriverProviderClient
+	provider = p.resolve(provider)
 
 	// load a client,
 	p.lock.RLock()
@@ -123,35 +149,29 @@ func (p *PluginClientBuilder) Get(_ context.Context, provider string) (v1alpha1.
 		return nil, fmt.Errorf("%w: provider %q", errInvalidProvider, provider)
 	}
 
-	// check all paths
-	socketPath := ""
-	for k := range p.socketPaths {
-		tryPath := filepath.Join(p.socketPaths[k], provider+".sock")
-		if _, err := os.Stat(tryPath); err == nil {
-			socketPath = tryPath
-			break
+	opts := slices.Concat(p.opts, []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(p.mountGuardFor(provider).unaryClientInterceptor),
+	})
+	var conn *grpc.ClientConn
+	var err error
+	if remote, ok := p.remoteProvider(provider); ok {
+		if conn, err = remote.dial(opts); err != nil {
+			return nil, err
 		}
-		// TODO: This is a workaround for Windows 20H2 issue for os.Stat(). See
-		// microsoft/Windows-Containers#97 for details.
-		// Once the issue is resolved, the following os.Lstat() is not needed.
-		if runtimeutil.IsRuntimeWindows() {
-			if _, err := os.Lstat(tryPath); err == nil {
-				socketPath = tryPath
-				break
-			}
+	} else {
+		socketPath := p.findSocket(provider)
+		if socketPath == "" {
+			return nil, fmt.Errorf("%w: provider %q", ErrProviderNotFound, provider)
 		}
-	}
 
-	if socketPath == "" {
-		return nil, fmt.Errorf("%w: provider %q", errProviderNotFound, provider)
-	}
-
-	conn, err := grpc.NewClient(
-		"unix://"+socketPath,
-		p.opts...,
-	)
-	if err != nil {
-		return nil, err
+		if conn, err = grpc.NewClient(
+			"unix://"+socketPath,
+			slices.Concat(opts, []grpc.DialOption{
+				grpc.WithTransportCredentials(insecure.NewCredentials()), // the interface is only secured through filesystem ACLs
+			})...,
+		); err != nil {
+			return nil, err
+		}
 	}
 	out = v1alpha1.NewCSIDriverProviderClient(conn)
 
@@ -169,6 +189,26 @@ func (p *PluginClientBuilder) Get(_ context.Context, provider string) (v1alpha1.
 	return out, nil
 }
 
+// findSocket returns the path of the socket of the provider in the first
+// provider volume path where it exists, or an empty string.
+func (p *PluginClientBuilder) findSocket(provider string) string {
+	for k := range p.socketPaths {
+		tryPath := filepath.Join(p.socketPaths[k], provider+".sock")
+		if _, err := os.Stat(tryPath); err == nil {
+			return tryPath
+		}
+		// TODO: This is a workaround for Windows 20H2 issue for os.Stat(). See
+		// microsoft/Windows-Containers#97 for details.
+		// Once the issue is resolved, the following os.Lstat() is not needed.
+		if runtimeutil.IsRuntimeWindows() {
+			if _, err := os.Lstat(tryPath); err == nil {
+				return tryPath
+			}
+		}
+	}
+	return ""
+}
+
 // Cleanup closes all underlying connections and removes all clients.
 func (p *PluginClientBuilder) Cleanup() {
 	p.lock.Lock()
@@ -183,41 +223,17 @@ func (p *PluginClientBuilder) Cleanup() {
 	p.conns = make(map[string]*grpc.ClientConn)
 }
 
-// HealthCheck enables periodic healthcheck for configured provider clients by making
-// a Version() RPC call. If the provider healthcheck fails, we log an error.
-//
-// This method blocks until the parent context is canceled during termination.
-func (p *PluginClientBuilder) HealthCheck(ctx context.Context, interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			p.lock.RLock()
-
-			for provider, client := range p.clients {
-				c, cancel := context.WithTimeout(ctx, 5*time.Second)
-				defer cancel()
-
-				runtimeVersion, err := Version(c, client)
-				if err != nil {
-					klog.V(5).ErrorS(err, "provider healthcheck failed", "provider", provider)
-					continue
-				}
-				klog.V(5).InfoS("provider healthcheck successful", "provider", provider, "runtimeVersion", runtimeVersion)
-			}
-
-			p.lock.RUnlock()
-		}
-	}
-}
-
 // MountContent calls the client's Mount() RPC with helpers to format the
 // request and interpret the response.
-func MountContent(ctx context.Context, client v1alpha1.CSIDriverProviderClient, attributes, secrets string, oldObjectVersions map[string]string) (map[string]string, map[string][]byte, error) {
+func MountContent(ctx context.Context, client v1alpha1.CSIDriverProviderClient, providerName, attributes, secrets string, oldObjectVersions map[string]string) (_ map[string]string, _ map[string][]byte, err error) {
+	ctx, span := otel.Tracer(scope).Start(ctx, "MountContent", trace.WithAttributes(attribute.String(providerKey, providerName)))
+	defer func() {
+		if err != nil {
+			span.SetStatus(otelcodes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	objVersions := make([]*v1alpha1.ObjectVersion, 0, len(oldObjectVersions))
 	for obj, version := range oldObjectVersions {
 		objVersions = append(objVersions, &v1alpha1.ObjectVersion{Id: obj, Version: version})
@@ -237,15 +253,19 @@ func MountContent(ctx context.Context, client v1alpha1.CSIDriverProviderClient,
 		TargetPath:           "/mnt/secrets-store",
 	}
 
+	start := time.Now()
 	resp, err := client.Mount(ctx, req)
 	if err != nil {
+		getStatsReporter().reportMount(ctx, providerName, status.Code(err), time.Since(start), true)
 		if isMaxRecvMsgSizeError(err) {
 			klog.ErrorS(err, "Set --max-call-recv-msg-size to configure larger maximum size in bytes of gRPC response")
 		}
 		return nil, nil, err
 	}
 	klog.V(5).Info("finished mount request")
-	if resp != nil && resp.GetError() != nil && len(resp.GetError().Code) > 0 {
+	providerFailed := resp != nil && resp.GetError() != nil && len(resp.GetError().Code) > 0
+	getStatsReporter().reportMount(ctx, providerName, codes.OK, time.Since(start), providerFailed)
+	if providerFailed {
 		return nil, nil, fmt.Errorf("mount request failed with provider error code %s", resp.GetError().Code)
 	}
 
@@ -261,7 +281,9 @@ func MountContent(ctx context.Context, client v1alpha1.CSIDriverProviderClient,
 	// warn if the proto response size is over 1 MiB.
 	// Individual k8s secrets are limited to 1MiB in size.
 	// Ref: https://kubernetes.io/docs/concepts/configuration/secret/#restrictions
-	if size := proto.Size(resp); size > 1048576 {
+	size := proto.Size(resp)
+	getStatsReporter().reportMountResponseSize(ctx, providerName, size)
+	if size > 1048576 {
 		klog.InfoS("proto above 1MiB, secret sync may fail", "size", size)
 	}
 
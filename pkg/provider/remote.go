@@ -0,0 +1,153 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"sigs.k8s.io/yaml"
+)
+
+// RemoteProvidersConfig is the configuration file of the remote providers.
+type RemoteProvidersConfig struct {
+	Providers []RemoteProvider `json:"providers"`
+}
+
+// RemoteProvider is a provider reached over TCP with mutual TLS instead of
+// a unix domain socket in a provider volume path.
+type RemoteProvider struct {
+	// Name is the name of the provider in the SecretProviderClasses.
+	Name string `json:"name"`
+	// Target is the gRPC target of the provider, e.g.
+	// dns:///provider.namespace.svc:8443.
+	Target string `json:"target"`
+	// CAFile is the path of the CA bundle verifying the provider certificate.
+	CAFile string `json:"caFile"`
+	// CertFile and KeyFile are the paths of the client certificate and key
+	// presented to the provider. They are read again on every handshake so
+	// a rotated certificate is picked up without a restart.
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+	// ServerName overrides the name verified in the provider certificate,
+	// which defaults to the host of the target.
+	ServerName string `json:"serverName,omitempty"`
+}
+
+// LoadRemoteProviders reads and validates the remote providers configuration
+// file.
+func LoadRemoteProviders(path string) ([]RemoteProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remote providers config: %w", err)
+	}
+	var config RemoteProvidersConfig
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse remote providers config %s: %w", path, err)
+	}
+
+	var names []string
+	for _, remote := range config.Providers {
+		if err := remote.validate(); err != nil {
+			return nil, err
+		}
+		if slices.Contains(names, remote.Name) {
+			return nil, fmt.Errorf("remote provider %q is configured more than once", remote.Name)
+		}
+		names = append(names, remote.Name)
+	}
+	return config.Providers, nil
+}
+
+func (r RemoteProvider) validate() error {
+	if !pluginNameRe.MatchString(r.Name) || len(r.Name) == 0 {
+		return fmt.Errorf("%w: remote provider %q", errInvalidProvider, r.Name)
+	}
+	if address, ok := strings.CutPrefix(r.Target, "dns:///"); !ok || len(address) == 0 {
+		return fmt.Errorf("invalid target %q of remote provider %q, expected dns:///host:port", r.Target, r.Name)
+	}
+	if len(r.CAFile) == 0 || len(r.CertFile) == 0 || len(r.KeyFile) == 0 {
+		return fmt.Errorf("remote provider %q requires caFile, certFile and keyFile", r.Name)
+	}
+	return nil
+}
+
+// tlsConfig returns the mutual TLS configuration of the connection to the
+// remote provider.
+func (r RemoteProvider) tlsConfig() (*tls.Config, error) {
+	ca, err := os.ReadFile(r.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file of remote provider %q: %w", r.Name, err)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate found in CA file %s of remote provider %q", r.CAFile, r.Name)
+	}
+	// fail early on a missing or invalid client certificate
+	if _, err := tls.LoadX509KeyPair(r.CertFile, r.KeyFile); err != nil {
+		return nil, fmt.Errorf("failed to load client certificate of remote provider %q: %w", r.Name, err)
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    rootCAs,
+		ServerName: r.ServerName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(r.CertFile, r.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client certificate of remote provider %q: %w", r.Name, err)
+			}
+			return &cert, nil
+		},
+	}, nil
+}
+
+// dial creates the connection to the remote provider.
+func (r RemoteProvider) dial(opts []grpc.DialOption) (*grpc.ClientConn, error) {
+	tlsConfig, err := r.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return grpc.NewClient(r.Target, slices.Concat(opts, []grpc.DialOption{
+		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
+	})...)
+}
+
+// SetRemoteProviders sets the providers reached over TCP with mutual TLS. A
+// remote provider takes precedence over a socket with the same name. It must
+// be called before the plugin client builder is used.
+func (p *PluginClientBuilder) SetRemoteProviders(remotes []RemoteProvider) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.remotes = make(map[string]RemoteProvider, len(remotes))
+	for _, remote := range remotes {
+		p.remotes[remote.Name] = remote
+	}
+}
+
+func (p *PluginClientBuilder) remoteProvider(provider string) (RemoteProvider, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	remote, ok := p.remotes[provider]
+	return remote, ok
+}
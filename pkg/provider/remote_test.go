@@ -0,0 +1,250 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	providerfake "sigs.k8s.io/secrets-store-csi-driver/provider/fake"
+	"sigs.k8s.io/secrets-store-csi-driver/provider/v1alpha1"
+)
+
+// testCA issues the certificates of the remote provider tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM encoded certificate and key for the name, valid for
+// both server and client authentication.
+func (ca *testCA) issue(t *testing.T, name string) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// newRemoteProvider starts a fake provider over TCP requiring a client
+// certificate issued by the CA, and returns its address.
+func newRemoteProvider(t *testing.T, ca *testCA) string {
+	t.Helper()
+
+	certPEM, keyPEM := ca.issue(t, "localhost")
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	})))
+	v1alpha1.RegisterCSIDriverProviderServer(server, &providerfake.MockCSIProviderServer{})
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestRemoteProvider(t *testing.T) {
+	ca := newTestCA(t)
+	address := newRemoteProvider(t, ca)
+
+	dir := t.TempDir()
+	caFile := writeFile(t, dir, "ca.crt", ca.pem)
+	certPEM, keyPEM := ca.issue(t, "secrets-store-sync-controller")
+	certFile := writeFile(t, dir, "tls.crt", certPEM)
+	keyFile := writeFile(t, dir, "tls.key", keyPEM)
+
+	// a client certificate which is not issued by the CA of the provider
+	untrustedCertPEM, untrustedKeyPEM := newTestCA(t).issue(t, "secrets-store-sync-controller")
+	untrustedCertFile := writeFile(t, dir, "untrusted.crt", untrustedCertPEM)
+	untrustedKeyFile := writeFile(t, dir, "untrusted.key", untrustedKeyPEM)
+
+	config := writeFile(t, dir, "remote-providers.yaml", []byte(`providers:
+- name: remote-provider
+  target: dns:///`+address+`
+  caFile: `+caFile+`
+  certFile: `+certFile+`
+  keyFile: `+keyFile+`
+  serverName: localhost
+- name: untrusted-provider
+  target: dns:///`+address+`
+  caFile: `+caFile+`
+  certFile: `+untrustedCertFile+`
+  keyFile: `+untrustedKeyFile+`
+`))
+	remotes, err := LoadRemoteProviders(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pcb := NewPluginClientBuilder([]string{"/nonexistent"})
+	t.Cleanup(pcb.Cleanup)
+	pcb.SetRemoteProviders(remotes)
+	pcb.SetAliases(map[string]string{"remote": "remote-provider"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := pcb.Get(ctx, "remote")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	runtimeVersion, err := Version(ctx, client)
+	assert.NoError(t, err)
+	assert.Equal(t, "0.0.10", runtimeVersion)
+
+	objectVersions, files, err := MountContent(ctx, client, "remote", "{}", "{}", nil)
+	assert.EqualError(t, err, errMissingObjectVersions.Error())
+	assert.Nil(t, objectVersions)
+	assert.Nil(t, files)
+
+	client, err = pcb.Get(ctx, "untrusted-provider")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	untrustedCtx, untrustedCancel := context.WithTimeout(ctx, time.Second)
+	defer untrustedCancel()
+	_, err = Version(untrustedCtx, client)
+	assert.Error(t, err, "expected the provider to reject an untrusted client certificate")
+}
+
+func TestLoadRemoteProviders(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{
+			name:          "unknown field",
+			config:        "providers:\n- name: vault\n  address: vault:8443\n",
+			expectedError: `unknown field "address"`,
+		},
+		{
+			name:          "invalid name",
+			config:        "providers:\n- name: ../vault\n  target: dns:///vault:8443\n  caFile: ca.crt\n  certFile: tls.crt\n  keyFile: tls.key\n",
+			expectedError: `invalid provider: remote provider "../vault"`,
+		},
+		{
+			name:          "invalid target",
+			config:        "providers:\n- name: vault\n  target: vault:8443\n  caFile: ca.crt\n  certFile: tls.crt\n  keyFile: tls.key\n",
+			expectedError: `invalid target "vault:8443" of remote provider "vault", expected dns:///host:port`,
+		},
+		{
+			name:          "missing credentials",
+			config:        "providers:\n- name: vault\n  target: dns:///vault:8443\n  caFile: ca.crt\n",
+			expectedError: `remote provider "vault" requires caFile, certFile and keyFile`,
+		},
+		{
+			name:          "duplicate provider",
+			config:        "providers:\n- name: vault\n  target: dns:///vault:8443\n  caFile: ca.crt\n  certFile: tls.crt\n  keyFile: tls.key\n- name: vault\n  target: dns:///vault:8443\n  caFile: ca.crt\n  certFile: tls.crt\n  keyFile: tls.key\n",
+			expectedError: `remote provider "vault" is configured more than once`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadRemoteProviders(writeFile(t, t.TempDir(), "remote-providers.yaml", []byte(tt.config)))
+			assert.ErrorContains(t, err, tt.expectedError)
+		})
+	}
+}
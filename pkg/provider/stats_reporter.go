@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"google.golang.org/grpc/codes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	scope = "sigs.k8s.io/secrets-store-sync-controller"
+
+	providerKey       = "provider"
+	grpcCodeKey       = "grpc_code"
+	runtimeVersionKey = "runtime_version"
+)
+
+type reporter struct {
+	meter metric.Meter
+
+	mountDuration     metric.Float64Histogram
+	mountErrorsTotal  metric.Int64Counter
+	mountResponseSize metric.Int64Histogram
+	providerUp        metric.Int64ObservableGauge
+	providerInfo      metric.Int64ObservableGauge
+}
+
+var (
+	statsReporter     *reporter
+	statsReporterOnce sync.Once
+)
+
+// getStatsReporter returns the reporter of the provider RPCs. The instruments
+// are created on first use so they are bound to the meter provider of the
+// configured metrics backend.
+func getStatsReporter() *reporter {
+	statsReporterOnce.Do(func() {
+		var err error
+		if statsReporter, err = newStatsReporter(otel.Meter(scope)); err != nil {
+			klog.ErrorS(err, "failed to create provider metrics, provider RPCs will not be reported")
+			statsReporter, _ = newStatsReporter(noop.NewMeterProvider().Meter(scope))
+		}
+	})
+	return statsReporter
+}
+
+func newStatsReporter(meter metric.Meter) (*reporter, error) {
+	var err error
+
+	r := &reporter{meter: meter}
+	if r.mountDuration, err = meter.Float64Histogram(
+		"provider_mount_duration_seconds",
+		metric.WithDescription("Duration of the Mount RPCs to the providers"),
+		metric.WithUnit("s"),
+	); err != nil {
+		return nil, err
+	}
+	if r.mountErrorsTotal, err = meter.Int64Counter(
+		"provider_mount_errors_total",
+		metric.WithDescription("Total number of failed Mount RPCs to the providers"),
+	); err != nil {
+		return nil, err
+	}
+	if r.mountResponseSize, err = meter.Int64Histogram(
+		"provider_mount_response_size_bytes",
+		metric.WithDescription("Size of the Mount responses of the providers"),
+		metric.WithUnit("By"),
+		// from 1KiB up to the 4MiB default maximum gRPC message size
+		metric.WithExplicitBucketBoundaries(1<<10, 4<<10, 16<<10, 64<<10, 256<<10, 1<<20, 4<<20),
+	); err != nil {
+		return nil, err
+	}
+	if r.providerUp, err = meter.Int64ObservableGauge(
+		"provider_up",
+		metric.WithDescription("Whether the last healthcheck of the provider succeeded (1) or failed (0)"),
+	); err != nil {
+		return nil, err
+	}
+	if r.providerInfo, err = meter.Int64ObservableGauge(
+		"provider_info",
+		metric.WithDescription("Runtime version reported by the provider in its last successful healthcheck, always 1"),
+	); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reportMount reports a Mount RPC. The code is the gRPC status code of the
+// RPC; provider errors returned in a successful response are reported with
+// the OK code.
+func (r *reporter) reportMount(ctx context.Context, provider string, code codes.Code, duration time.Duration, failed bool) {
+	opt := metric.WithAttributes(
+		attribute.Key(providerKey).String(provider),
+		attribute.Key(grpcCodeKey).String(code.String()),
+	)
+	r.mountDuration.Record(ctx, duration.Seconds(), opt)
+	if failed {
+		r.mountErrorsTotal.Add(ctx, 1, opt)
+	}
+}
+
+func (r *reporter) reportMountResponseSize(ctx context.Context, provider string, size int) {
+	r.mountResponseSize.Record(ctx, int64(size), metric.WithAttributes(
+		attribute.Key(providerKey).String(provider),
+	))
+}
+
+// registerHealthCallback reports the provider healthcheck results of the
+// plugin client builder until the returned function is called.
+func (r *reporter) registerHealthCallback(p *PluginClientBuilder) (func(), error) {
+	registration, err := r.meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for provider, health := range p.providersHealth() {
+			up := int64(0)
+			if health.healthy {
+				up = 1
+				o.ObserveInt64(r.providerInfo, 1, metric.WithAttributes(
+					attribute.Key(providerKey).String(provider),
+					attribute.Key(runtimeVersionKey).String(health.runtimeVersion),
+				))
+			}
+			o.ObserveInt64(r.providerUp, up, metric.WithAttributes(
+				attribute.Key(providerKey).String(provider),
+			))
+		}
+		return nil
+	}, r.providerUp, r.providerInfo)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := registration.Unregister(); err != nil {
+			klog.ErrorS(err, "failed to unregister provider health metrics")
+		}
+	}, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"sigs.k8s.io/secrets-store-csi-driver/provider/v1alpha1"
+)
+
+// traceparentServer records the trace context propagated by the client.
+type traceparentServer struct {
+	v1alpha1.UnimplementedCSIDriverProviderServer
+
+	traceparent string
+}
+
+func (s *traceparentServer) Mount(ctx context.Context, _ *v1alpha1.MountRequest) (*v1alpha1.MountResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("traceparent"); len(values) > 0 {
+		s.traceparent = values[0]
+	}
+	return &v1alpha1.MountResponse{
+		ObjectVersion: []*v1alpha1.ObjectVersion{{Id: "foo", Version: "v1"}},
+		Files:         []*v1alpha1.File{{Path: "foo", Contents: []byte("foo")}},
+	}, nil
+}
+
+func TestMountContentPropagatesTraceContext(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	previousTracerProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousTracerProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	// t.TempDir() might be too long for a unix socket path
+	socketPath, err := os.MkdirTemp("/tmp", "provider-tracing-test-")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(socketPath)
+	})
+	lis, err := net.Listen("unix", filepath.Join(socketPath, "fake-provider.sock"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fakeServer := &traceparentServer{}
+	server := grpc.NewServer()
+	v1alpha1.RegisterCSIDriverProviderServer(server, fakeServer)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	pcb := NewPluginClientBuilder([]string{socketPath})
+	t.Cleanup(pcb.Cleanup)
+	client, err := pcb.Get(context.Background(), "fake-provider")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, parent := tracerProvider.Tracer("test").Start(context.Background(), "parent")
+	_, files, err := MountContent(ctx, client, "fake-provider", "{}", "{}", nil)
+	parent.End()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(files["foo"]) != "foo" {
+		t.Fatalf("expected the file foo to be mounted, got %v", files)
+	}
+
+	traceID := parent.SpanContext().TraceID().String()
+	if !strings.Contains(fakeServer.traceparent, traceID) {
+		t.Fatalf("expected the traceparent %q to contain the trace ID %s", fakeServer.traceparent, traceID)
+	}
+
+	names := map[string]bool{}
+	for _, span := range spanRecorder.Ended() {
+		if span.SpanContext().TraceID().String() == traceID {
+			names[span.Name()] = true
+		}
+	}
+	for _, name := range []string{"MountContent", "v1alpha1.CSIDriverProvider/Mount"} {
+		if !names[name] {
+			t.Errorf("expected a %s span in the trace, got %v", name, names)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/klog/v2"
+)
+
+const socketSuffix = ".sock"
+
+// ProvidersListener is called with the discovered providers, and the ones
+// which were not discovered before, every time the provider set changes.
+type ProvidersListener func(providers, added []string)
+
+// AddProvidersListener registers a listener for the changes of the providers
+// discovered by Watch.
+func (p *PluginClientBuilder) AddProvidersListener(listener ProvidersListener) {
+	p.providersLock.Lock()
+	defer p.providersLock.Unlock()
+	p.listeners = append(p.listeners, listener)
+}
+
+// Providers returns the names of the providers whose socket was discovered in
+// the provider volume paths, sorted.
+func (p *PluginClientBuilder) Providers() []string {
+	p.providersLock.RLock()
+	defer p.providersLock.RUnlock()
+	return slices.Clone(p.providers)
+}
+
+// Watch watches the provider volume paths for provider sockets. The client of
+// a provider whose socket is replaced is redialed, and the client of a provider
+// whose socket is removed is closed, instead of relying on the reconnection of
+// gRPC to a stale path. Every change of the discovered providers is published
+// to the listeners.
+//
+// This method blocks until the parent context is canceled during termination.
+func (p *PluginClientBuilder) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create provider volume watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	for _, path := range p.socketPaths {
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("failed to watch provider volume %s: %w", path, err)
+		}
+	}
+	p.discoverProviders()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			p.handleSocketEvent(ctx, event)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			klog.ErrorS(err, "provider volume watcher error")
+		}
+	}
+}
+
+func (p *PluginClientBuilder) handleSocketEvent(ctx context.Context, event fsnotify.Event) {
+	provider, ok := strings.CutSuffix(filepath.Base(event.Name), socketSuffix)
+	if !ok || !pluginNameRe.MatchString(provider) {
+		return
+	}
+	if _, remote := p.remoteProvider(provider); remote {
+		// the client of a remote provider is not bound to a socket
+		return
+	}
+
+	switch {
+	case event.Has(fsnotify.Create):
+		klog.InfoS("provider socket created, redialing provider", "provider", provider, "socket", event.Name)
+		p.evict(provider)
+		if err := p.ValidateSockets(); err != nil {
+			klog.ErrorS(err, "invalid provider sockets")
+		}
+		if _, err := p.Get(ctx, provider); err != nil {
+			klog.ErrorS(err, "failed to redial provider", "provider", provider)
+		}
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		klog.InfoS("provider socket removed, closing provider client", "provider", provider, "socket", event.Name)
+		p.evict(provider)
+	default:
+		return
+	}
+	p.discoverProviders()
+}
+
+// evict closes the connection to the provider and removes its client, the
+// next Get dials the provider again.
+func (p *PluginClientBuilder) evict(provider string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if conn, ok := p.conns[provider]; ok {
+		if err := conn.Close(); err != nil {
+			klog.ErrorS(err, "error shutting down provider connection", "provider", provider)
+		}
+	}
+	delete(p.conns, provider)
+	delete(p.clients, provider)
+}
+
+// discoverProviders lists the provider sockets in the provider volume paths
+// and notifies the listeners if the discovered providers changed.
+func (p *PluginClientBuilder) discoverProviders() {
+	var providers []string
+	for _, path := range p.socketPaths {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			klog.ErrorS(err, "failed to list provider volume", "path", path)
+			continue
+		}
+		for _, entry := range entries {
+			provider, ok := strings.CutSuffix(entry.Name(), socketSuffix)
+			if ok && pluginNameRe.MatchString(provider) && !slices.Contains(providers, provider) {
+				providers = append(providers, provider)
+			}
+		}
+	}
+	slices.Sort(providers)
+
+	p.providersLock.Lock()
+	previous := p.providers
+	p.providers = providers
+	listeners := slices.Clone(p.listeners)
+	p.providersLock.Unlock()
+
+	if slices.Equal(previous, providers) {
+		return
+	}
+
+	var added []string
+	for _, provider := range providers {
+		if !slices.Contains(previous, provider) {
+			added = append(added, provider)
+		}
+	}
+	klog.InfoS("discovered providers changed", "providers", providers, "added", added)
+	for _, listener := range listeners {
+		listener(slices.Clone(providers), added)
+	}
+}
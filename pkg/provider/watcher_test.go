@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	providerfake "sigs.k8s.io/secrets-store-csi-driver/provider/fake"
+)
+
+func TestWatch(t *testing.T) {
+	socketPath := newFakeProvider(t, "provider-a")
+
+	pcb := NewPluginClientBuilder([]string{socketPath})
+	t.Cleanup(pcb.Cleanup)
+
+	var (
+		lock  sync.Mutex
+		added []string
+	)
+	pcb.AddProvidersListener(func(_, newProviders []string) {
+		lock.Lock()
+		defer lock.Unlock()
+		added = append(added, newProviders...)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watchErr := make(chan error)
+	go func() {
+		watchErr <- pcb.Watch(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-watchErr; err != nil {
+			t.Errorf("unexpected watch error: %v", err)
+		}
+	})
+
+	eventually := func(condition func() bool, message string) {
+		t.Helper()
+		for start := time.Now(); !condition(); time.Sleep(10 * time.Millisecond) {
+			if time.Since(start) > 5*time.Second {
+				t.Fatal(message)
+			}
+		}
+	}
+	hasClient := func(provider string) bool {
+		pcb.lock.RLock()
+		defer pcb.lock.RUnlock()
+		_, ok := pcb.clients[provider]
+		return ok
+	}
+
+	eventually(func() bool {
+		return slices.Equal(pcb.Providers(), []string{"provider-a"})
+	}, "expected provider-a to be discovered")
+
+	// a new provider is discovered and dialed
+	server, err := providerfake.NewMocKCSIProviderServer(filepath.Join(socketPath, "provider-b.sock"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	eventually(func() bool {
+		return slices.Equal(pcb.Providers(), []string{"provider-a", "provider-b"}) && hasClient("provider-b")
+	}, "expected provider-b to be discovered")
+
+	lock.Lock()
+	if !slices.Equal(added, []string{"provider-a", "provider-b"}) {
+		t.Errorf("expected the listener to be notified of provider-a and provider-b, got %v", added)
+	}
+	lock.Unlock()
+
+	// the client of a removed provider is closed
+	server.Stop()
+	if err := os.Remove(filepath.Join(socketPath, "provider-b.sock")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	eventually(func() bool {
+		return slices.Equal(pcb.Providers(), []string{"provider-a"}) && !hasClient("provider-b")
+	}, "expected provider-b to be removed")
+
+	if _, err := pcb.Get(context.Background(), "provider-b"); !errors.Is(err, ErrProviderNotFound) {
+		t.Errorf("expected provider-b not to be found, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package token
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	scope = "sigs.k8s.io/secrets-store-sync-controller"
+
+	namespaceKey = "namespace"
+	resultKey    = "result"
+
+	resultHit  = "hit"
+	resultMiss = "miss"
+)
+
+type reporter struct {
+	cacheRequestsTotal  metric.Int64Counter
+	tokenRequestsFailed metric.Int64Counter
+}
+
+func newStatsReporter() (*reporter, error) {
+	var err error
+
+	r := &reporter{}
+	meter := otel.Meter(scope)
+
+	if r.cacheRequestsTotal, err = meter.Int64Counter(
+		"token_cache_requests_total",
+		metric.WithDescription("Total number of service account token lookups by cache result"),
+	); err != nil {
+		return nil, err
+	}
+	if r.tokenRequestsFailed, err = meter.Int64Counter(
+		"token_request_errors_total",
+		metric.WithDescription("Total number of failed TokenRequests"),
+	); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reportCacheRequest reports whether a cached token was used. The reporter may
+// be nil, in which case nothing is reported.
+func (r *reporter) reportCacheRequest(namespace string, hit bool) {
+	if r == nil {
+		return
+	}
+	result := resultMiss
+	if hit {
+		result = resultHit
+	}
+	r.cacheRequestsTotal.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.Key(namespaceKey).String(namespace),
+		attribute.Key(resultKey).String(result),
+	))
+}
+
+// reportTokenRequestError reports a failed TokenRequest. The reporter may be
+// nil, in which case nothing is reported.
+func (r *reporter) reportTokenRequestError(namespace string) {
+	if r == nil {
+		return
+	}
+	r.tokenRequestsFailed.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.Key(namespaceKey).String(namespace),
+	))
+}
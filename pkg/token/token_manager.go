@@ -83,6 +83,12 @@ func NewManager(c clientset.Interface) *Manager {
 		cache: make(map[string]*authenticationv1.TokenRequest),
 		clock: clock.RealClock{},
 	}
+	statsReporter, err := newStatsReporter()
+	if err != nil {
+		klog.ErrorS(err, "failed to create token metrics, token requests will not be reported")
+	} else {
+		m.statsReporter = statsReporter
+	}
 	go wait.Forever(m.cleanup, gcPeriod)
 	return m
 }
@@ -97,6 +103,9 @@ type Manager struct {
 	// mocked for testing
 	getToken func(name, namespace string, tr *authenticationv1.TokenRequest) (*authenticationv1.TokenRequest, error)
 	clock    clock.Clock
+
+	// statsReporter reports the cache and TokenRequest metrics, nil if disabled
+	statsReporter *reporter
 }
 
 // GetServiceAccountToken gets a service account token for a pod from cache or
@@ -113,11 +122,14 @@ func (m *Manager) GetServiceAccountToken(namespace, name string, tr *authenticat
 	ctr, ok := m.get(key)
 
 	if ok && !m.requiresRefresh(ctr) {
+		m.statsReporter.reportCacheRequest(namespace, true)
 		return ctr, nil
 	}
+	m.statsReporter.reportCacheRequest(namespace, false)
 
 	tr, err := m.getToken(name, namespace, tr)
 	if err != nil {
+		m.statsReporter.reportTokenRequestError(namespace)
 		switch {
 		case !ok:
 			return nil, fmt.Errorf("failed to fetch token: %w", err)
@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"k8s.io/klog/v2"
+)
+
+var (
+	tracingBackend     = flag.String("tracing-backend", "none", "Backend the traces are exported to: none, otlp (OTLP over gRPC, also otlp-grpc) or otlp-http")
+	tracingEndpoint    = flag.String("tracing-endpoint", "", "Address (host:port) of the OpenTelemetry collector the traces are exported to. Defaults to the OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT environment variables, or the local collector.")
+	tracingInsecure    = flag.Bool("tracing-insecure", false, "Export the traces to the OpenTelemetry collector without TLS. The CA bundle, client certificate and headers are set with the OTEL_EXPORTER_OTLP_* environment variables.")
+	tracingSampleRatio = flag.Float64("tracing-sample-ratio", 1, "Ratio of the traces which are sampled, between 0 and 1. Traces started by a sampled parent are always sampled.")
+)
+
+const (
+	noneBackend     = "none"
+	otlpBackend     = "otlp"
+	otlpGRPCBackend = "otlp-grpc"
+	otlpHTTPBackend = "otlp-http"
+
+	serviceName = "secrets-store-sync-controller"
+)
+
+// InitTracing sets up the global tracer provider with the configured tracing
+// backend and the W3C trace context propagator. The returned function flushes
+// the pending spans and stops the exporter.
+func InitTracing() (func(context.Context) error, error) {
+	tb := strings.ToLower(*tracingBackend)
+	klog.InfoS("initializing tracing backend", "backend", tb)
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if *tracingSampleRatio < 0 || *tracingSampleRatio > 1 {
+		return nil, fmt.Errorf("invalid --tracing-sample-ratio %v, it must be between 0 and 1", *tracingSampleRatio)
+	}
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch tb {
+	case noneBackend:
+		return func(context.Context) error { return nil }, nil
+	case otlpBackend, otlpGRPCBackend:
+		var opts []otlptracegrpc.Option
+		if len(*tracingEndpoint) > 0 {
+			opts = append(opts, otlptracegrpc.WithEndpoint(*tracingEndpoint))
+		}
+		if *tracingInsecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(context.Background(), opts...)
+	case otlpHTTPBackend:
+		var opts []otlptracehttp.Option
+		if len(*tracingEndpoint) > 0 {
+			opts = append(opts, otlptracehttp.WithEndpoint(*tracingEndpoint))
+		}
+		if *tracingInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	default:
+		return nil, fmt.Errorf("unsupported tracing backend %v", *tracingBackend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(*tracingSampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+
+	otel.SetTracerProvider(tracerProvider)
+
+	return tracerProvider.Shutdown, nil
+}
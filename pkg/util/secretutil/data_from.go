@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretutil
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	secretsyncv1alpha1 "sigs.k8s.io/secrets-store-sync-controller/api/v1alpha1"
+)
+
+// invalidKeyChars matches the characters that are not allowed in secret keys.
+var invalidKeyChars = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+
+// BuildKubeSecretDataFrom selects the files matching the dataFrom entries and
+// returns a map that will be populated in the Kubernetes secret data field.
+// The files are processed in the order of their path so that errors are
+// reported deterministically.
+func BuildKubeSecretDataFrom(dataFrom []secretsyncv1alpha1.SecretObjectDataFrom, secretType corev1.SecretType, files map[string][]byte) (map[string][]byte, error) {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+
+	datamap := make(map[string][]byte)
+	sources := make(map[string]string)
+	for i, df := range dataFrom {
+		for _, p := range paths {
+			selected, err := fileSelected(df, p)
+			if err != nil {
+				return datamap, fmt.Errorf("secretObject.dataFrom[%d]: %w", i, err)
+			}
+			if !selected {
+				continue
+			}
+
+			dataKey, err := dataFromKey(df, p)
+			if err != nil {
+				return datamap, fmt.Errorf("secretObject.dataFrom[%d]: %w", i, err)
+			}
+			if source, ok := sources[dataKey]; ok {
+				return datamap, fmt.Errorf("secretObject.dataFrom[%d]: files %s and %s are both synchronized to key %s", i, source, p, dataKey)
+			}
+			sources[dataKey] = p
+
+			datamap[dataKey] = files[p]
+			if secretType == corev1.SecretTypeTLS {
+				c, err := GetCertPart(files[p], dataKey)
+				if err != nil {
+					return datamap, fmt.Errorf("failed to get cert data for %s: %w", dataKey, err)
+				}
+				datamap[dataKey] = c
+			}
+		}
+	}
+	return datamap, nil
+}
+
+// fileSelected returns true if the file path is included and not excluded by
+// the dataFrom entry.
+func fileSelected(df secretsyncv1alpha1.SecretObjectDataFrom, filePath string) (bool, error) {
+	included := len(df.Include) == 0
+	for _, pattern := range df.Include {
+		ok, err := path.Match(pattern, filePath)
+		if err != nil {
+			return false, fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+		}
+		if ok {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false, nil
+	}
+
+	for _, pattern := range df.Exclude {
+		ok, err := path.Match(pattern, filePath)
+		if err != nil {
+			return false, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+		if ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// dataFromKey applies the rewrites of the dataFrom entry to the file path and
+// validates the resulting secret key according to the invalid key policy.
+func dataFromKey(df secretsyncv1alpha1.SecretObjectDataFrom, filePath string) (string, error) {
+	key := filePath
+	for _, rw := range df.Rewrite {
+		switch {
+		case len(rw.Prefix) > 0:
+			key = rw.Prefix + key
+		case len(rw.StripPrefix) > 0:
+			key = strings.TrimPrefix(key, rw.StripPrefix)
+		case rw.Regexp != nil:
+			re, err := regexp.Compile(rw.Regexp.Source)
+			if err != nil {
+				return "", fmt.Errorf("invalid rewrite regexp %q: %w", rw.Regexp.Source, err)
+			}
+			key = re.ReplaceAllString(key, rw.Regexp.Target)
+		}
+	}
+
+	if errs := validation.IsConfigMapKey(key); len(errs) > 0 && df.InvalidKeyPolicy == secretsyncv1alpha1.InvalidKeyPolicySanitize {
+		key = invalidKeyChars.ReplaceAllString(key, "_")
+	}
+	if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+		return "", fmt.Errorf("key %q for file %s is not a valid secret key: %s", key, filePath, strings.Join(errs, ", "))
+	}
+	return key, nil
+}
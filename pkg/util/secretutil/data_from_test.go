@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretutil
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	secretsyncv1alpha1 "sigs.k8s.io/secrets-store-sync-controller/api/v1alpha1"
+)
+
+func TestBuildKubeSecretDataFrom(t *testing.T) {
+	files := map[string][]byte{
+		"db-user":          []byte("admin"),
+		"db-password":      []byte("s3cr3t"),
+		"db-password.old":  []byte("old"),
+		"app/api-key":      []byte("key"),
+		"app/api-key:prod": []byte("prod"),
+	}
+
+	tests := []struct {
+		name                string
+		dataFrom            []secretsyncv1alpha1.SecretObjectDataFrom
+		files               map[string][]byte
+		expectedDataMap     map[string][]byte
+		expectedErrorString string
+	}{
+		{
+			name: "include and exclude",
+			dataFrom: []secretsyncv1alpha1.SecretObjectDataFrom{
+				{
+					Include: []string{"db-*"},
+					Exclude: []string{"*.old"},
+				},
+			},
+			expectedDataMap: map[string][]byte{
+				"db-user":     []byte("admin"),
+				"db-password": []byte("s3cr3t"),
+			},
+		},
+		{
+			name: "rewrites are applied in order",
+			dataFrom: []secretsyncv1alpha1.SecretObjectDataFrom{
+				{
+					Include: []string{"db-user", "db-password"},
+					Rewrite: []secretsyncv1alpha1.SecretKeyRewrite{
+						{StripPrefix: "db-"},
+						{Regexp: &secretsyncv1alpha1.SecretKeyRewriteRegexp{Source: "^(.*)$", Target: "${1}.txt"}},
+						{Prefix: "postgres."},
+					},
+				},
+			},
+			expectedDataMap: map[string][]byte{
+				"postgres.user.txt":     []byte("admin"),
+				"postgres.password.txt": []byte("s3cr3t"),
+			},
+		},
+		{
+			name: "invalid keys are rejected by default",
+			dataFrom: []secretsyncv1alpha1.SecretObjectDataFrom{
+				{
+					Include: []string{"app/*"},
+				},
+			},
+			expectedErrorString: `secretObject.dataFrom[0]: key "app/api-key" for file app/api-key is not a valid secret key: a valid config key must consist of alphanumeric characters, '-', '_' or '.' (e.g. 'key.name',  or 'KEY_NAME',  or 'key-name', regex used for validation is '[-._a-zA-Z0-9]+')`,
+		},
+		{
+			name: "invalid keys are sanitized",
+			dataFrom: []secretsyncv1alpha1.SecretObjectDataFrom{
+				{
+					Include:          []string{"app/*"},
+					InvalidKeyPolicy: secretsyncv1alpha1.InvalidKeyPolicySanitize,
+				},
+			},
+			expectedDataMap: map[string][]byte{
+				"app_api-key":      []byte("key"),
+				"app_api-key_prod": []byte("prod"),
+			},
+		},
+		{
+			name: "sanitized keys must not collide",
+			dataFrom: []secretsyncv1alpha1.SecretObjectDataFrom{
+				{
+					InvalidKeyPolicy: secretsyncv1alpha1.InvalidKeyPolicySanitize,
+				},
+			},
+			files: map[string][]byte{
+				"a/b": []byte("1"),
+				"a:b": []byte("2"),
+			},
+			expectedErrorString: "secretObject.dataFrom[0]: files a/b and a:b are both synchronized to key a_b",
+		},
+		{
+			name: "keys must not collide across entries",
+			dataFrom: []secretsyncv1alpha1.SecretObjectDataFrom{
+				{Include: []string{"db-user"}},
+				{Include: []string{"db-user"}},
+			},
+			expectedErrorString: "secretObject.dataFrom[1]: files db-user and db-user are both synchronized to key db-user",
+		},
+		{
+			name: "invalid pattern",
+			dataFrom: []secretsyncv1alpha1.SecretObjectDataFrom{
+				{Include: []string{"["}},
+			},
+			expectedErrorString: `secretObject.dataFrom[0]: invalid include pattern "[": syntax error in pattern`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testFiles := files
+			if test.files != nil {
+				testFiles = test.files
+			}
+
+			datamap, err := BuildKubeSecretDataFrom(test.dataFrom, corev1.SecretTypeOpaque, testFiles)
+			if len(test.expectedErrorString) > 0 {
+				if err == nil || err.Error() != test.expectedErrorString {
+					t.Fatalf("expected err: %+v, got: %+v", test.expectedErrorString, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(datamap, test.expectedDataMap) {
+				t.Fatalf("expected data map %q, got %q", test.expectedDataMap, datamap)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+// parseDocument parses the content of a file as a JSON or YAML document. The
+// parser errors are dropped as they may quote parts of the content.
+func parseDocument(content []byte, sourcePath string) (interface{}, error) {
+	jsonContent, err := yaml.YAMLToJSON(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file %s as JSON or YAML", sourcePath)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(jsonContent, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse file %s as JSON or YAML", sourcePath)
+	}
+	return doc, nil
+}
+
+// fieldValue returns the secret data for a field of a parsed document. Strings
+// are returned as is, anything else JSON encoded.
+func fieldValue(v interface{}) ([]byte, error) {
+	if s, ok := v.(string); ok {
+		return []byte(s), nil
+	}
+	return json.Marshal(v)
+}
+
+// extractField returns the single field of the file content selected by the
+// JSONPath expression.
+func extractField(content []byte, sourcePath, jsonPath string) ([]byte, error) {
+	expr := strings.TrimSpace(jsonPath)
+	if !strings.HasPrefix(expr, "{") {
+		expr = "{" + expr + "}"
+	}
+
+	jp := jsonpath.New(sourcePath)
+	if err := jp.Parse(expr); err != nil {
+		return nil, fmt.Errorf("invalid jsonPath %s for file %s: %w", jsonPath, sourcePath, err)
+	}
+
+	doc, err := parseDocument(content, sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := jp.FindResults(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find field %s in file %s: %w", jsonPath, sourcePath, err)
+	}
+
+	var values []interface{}
+	for _, result := range results {
+		for _, r := range result {
+			values = append(values, r.Interface())
+		}
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("field %s in file %s matched %d values, expected exactly one", jsonPath, sourcePath, len(values))
+	}
+
+	value, err := fieldValue(values[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode field %s in file %s", jsonPath, sourcePath)
+	}
+	return value, nil
+}
+
+// explodeObject returns each top-level property of the file content keyed by
+// the property name.
+func explodeObject(content []byte, sourcePath string) (map[string][]byte, error) {
+	doc, err := parseDocument(content, sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("file %s does not contain an object", sourcePath)
+	}
+
+	values := make(map[string][]byte, len(obj))
+	for _, property := range slices.Sorted(maps.Keys(obj)) {
+		v := obj[property]
+		if errs := validation.IsConfigMapKey(property); len(errs) > 0 {
+			return nil, fmt.Errorf("property %q of file %s is not a valid secret key: %s", property, sourcePath, strings.Join(errs, ", "))
+		}
+		value, err := fieldValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode property %s of file %s", property, sourcePath)
+		}
+		values[property] = value
+	}
+	return values, nil
+}
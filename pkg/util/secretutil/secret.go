@@ -22,6 +22,8 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"golang.org/x/crypto/pkcs12"
@@ -153,31 +155,70 @@ func getPrivateKey(data []byte) ([]byte, error) {
 }
 
 // BuildKubeSecretData gets the object contents from the pods target path and returns a
-// map that will be populated in the Kubernetes secret data field
-func BuildKubeSecretData(secretObjData []secretsyncv1alpha1.SecretObjectData, secretType corev1.SecretType, files map[string][]byte) (map[string][]byte, error) {
+// map that will be populated in the Kubernetes secret data field, along with the target
+// keys of the entries whose file is missing, or their source path for the exploded entries.
+// Missing files fail the build unless the entry is optional or the missing key policy is
+// not Fail.
+func BuildKubeSecretData(secretObjData []secretsyncv1alpha1.SecretObjectData, secretType corev1.SecretType, files map[string][]byte, missingKeyPolicy secretsyncv1alpha1.MissingKeyPolicy) (map[string][]byte, []string, error) {
 	datamap := make(map[string][]byte)
+	var missingKeys []string
 	for _, data := range secretObjData {
 		sourcePath := strings.TrimSpace(data.SourcePath)
 		dataKey := strings.TrimSpace(data.TargetKey)
 
 		if len(sourcePath) == 0 {
-			return datamap, fmt.Errorf("source path in secretObject.data is empty")
+			return datamap, missingKeys, fmt.Errorf("source path in secretObject.data is empty")
 		}
 		if len(dataKey) == 0 {
-			return datamap, fmt.Errorf("target key in secretObject.data is empty")
+			return datamap, missingKeys, fmt.Errorf("target key in secretObject.data is empty")
 		}
 		content, ok := files[sourcePath]
 		if !ok {
-			return datamap, fmt.Errorf("file matching sourcePath %s not found in the pod", sourcePath)
+			if data.Optional || (len(missingKeyPolicy) > 0 && missingKeyPolicy != secretsyncv1alpha1.MissingKeyPolicyFail) {
+				if data.Explode {
+					// the keys of an exploded entry are only known from the file
+					missingKeys = append(missingKeys, sourcePath)
+				} else {
+					missingKeys = append(missingKeys, dataKey)
+				}
+				continue
+			}
+			return datamap, missingKeys, fmt.Errorf("file matching sourcePath %s not found in the pod", sourcePath)
+		}
+
+		values := map[string][]byte{dataKey: content}
+		switch {
+		case data.Explode:
+			exploded, err := explodeObject(content, sourcePath)
+			if err != nil {
+				return datamap, missingKeys, err
+			}
+			values = exploded
+		case len(data.JSONPath) > 0:
+			field, err := extractField(content, sourcePath, data.JSONPath)
+			if err != nil {
+				return datamap, missingKeys, err
+			}
+			values[dataKey] = field
 		}
-		datamap[dataKey] = content
-		if secretType == corev1.SecretTypeTLS {
-			c, err := GetCertPart(content, dataKey)
+
+		for _, key := range slices.Sorted(maps.Keys(values)) {
+			if _, ok := datamap[key]; ok {
+				return datamap, missingKeys, fmt.Errorf("key %s is set more than once in secretObject.data", key)
+			}
+			value, err := applyTransforms(values[key], key, data.Transforms)
 			if err != nil {
-				return datamap, fmt.Errorf("failed to get cert data for %s: %w", dataKey, err)
+				return datamap, missingKeys, err
+			}
+			datamap[key] = value
+			if secretType == corev1.SecretTypeTLS {
+				c, err := GetCertPart(value, key)
+				if err != nil {
+					return datamap, missingKeys, fmt.Errorf("failed to get cert data for %s: %w", key, err)
+				}
+				datamap[key] = c
 			}
-			datamap[dataKey] = c
 		}
 	}
-	return datamap, nil
+	return datamap, missingKeys, nil
 }
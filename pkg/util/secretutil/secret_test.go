@@ -298,11 +298,125 @@ func TestGetSecretData(t *testing.T) {
 				"bar": []byte("test"),
 			},
 		},
+		{
+			name: "json field selected",
+			secretObjData: []secretsyncv1alpha1.SecretObjectData{
+				{
+					SourcePath: "creds",
+					TargetKey:  "password",
+					JSONPath:   "{.password}",
+				},
+				{
+					SourcePath: "creds",
+					TargetKey:  "port",
+					JSONPath:   ".db.port",
+				},
+			},
+			secretType: corev1.SecretTypeOpaque,
+			currentFiles: map[string][]byte{
+				"creds": []byte(`{"username":"admin","password":"s3cr3t","db":{"port":5432}}`),
+			},
+			expectedDataMap: map[string][]byte{
+				"password": []byte("s3cr3t"),
+				"port":     []byte("5432"),
+			},
+		},
+		{
+			name: "yaml field selected",
+			secretObjData: []secretsyncv1alpha1.SecretObjectData{
+				{
+					SourcePath: "creds",
+					TargetKey:  "password",
+					JSONPath:   "{.password}",
+				},
+			},
+			secretType: corev1.SecretTypeOpaque,
+			currentFiles: map[string][]byte{
+				"creds": []byte("username: admin\npassword: s3cr3t\n"),
+			},
+			expectedDataMap: map[string][]byte{
+				"password": []byte("s3cr3t"),
+			},
+		},
+		{
+			name: "missing field",
+			secretObjData: []secretsyncv1alpha1.SecretObjectData{
+				{
+					SourcePath: "creds",
+					TargetKey:  "password",
+					JSONPath:   "{.pass}",
+				},
+			},
+			secretType: corev1.SecretTypeOpaque,
+			currentFiles: map[string][]byte{
+				"creds": []byte(`{"password":"s3cr3t"}`),
+			},
+			expectedDataMap:     make(map[string][]byte),
+			expectedErrorString: "failed to find field {.pass} in file creds: pass is not found",
+		},
+		{
+			name: "parse failure does not leak the content",
+			secretObjData: []secretsyncv1alpha1.SecretObjectData{
+				{
+					SourcePath: "creds",
+					TargetKey:  "password",
+					JSONPath:   "{.password}",
+				},
+			},
+			secretType: corev1.SecretTypeOpaque,
+			currentFiles: map[string][]byte{
+				"creds": []byte("{s3cr3t"),
+			},
+			expectedDataMap:     make(map[string][]byte),
+			expectedErrorString: "failed to parse file creds as JSON or YAML",
+		},
+		{
+			name: "object exploded",
+			secretObjData: []secretsyncv1alpha1.SecretObjectData{
+				{
+					SourcePath: "creds",
+					TargetKey:  "creds",
+					Explode:    true,
+				},
+			},
+			secretType: corev1.SecretTypeOpaque,
+			currentFiles: map[string][]byte{
+				"creds": []byte(`{"username":"admin","password":"s3cr3t","port":5432}`),
+			},
+			expectedDataMap: map[string][]byte{
+				"username": []byte("admin"),
+				"password": []byte("s3cr3t"),
+				"port":     []byte("5432"),
+			},
+		},
+		{
+			name: "exploded keys must not collide",
+			secretObjData: []secretsyncv1alpha1.SecretObjectData{
+				{
+					SourcePath: "foo",
+					TargetKey:  "password",
+				},
+				{
+					SourcePath: "creds",
+					TargetKey:  "creds",
+					Explode:    true,
+				},
+			},
+			secretType: corev1.SecretTypeOpaque,
+			currentFiles: map[string][]byte{
+				"foo":   []byte("test"),
+				"creds": []byte(`{"password":"s3cr3t"}`),
+			},
+			expectedDataMap: map[string][]byte{
+				"password": []byte("test"),
+			},
+			expectedErrorString: "key password is set more than once in secretObject.data",
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			datamap, err := BuildKubeSecretData(test.secretObjData, test.secretType, test.currentFiles)
+			datamap, _, err := BuildKubeSecretData(test.secretObjData, test.secretType, test.currentFiles, secretsyncv1alpha1.MissingKeyPolicyFail)
 			if len(test.expectedErrorString) > 0 {
 				if err == nil || err.Error() != test.expectedErrorString {
 					t.Fatalf("expected err: %+v, got: %+v", test.expectedErrorString, err)
@@ -0,0 +1,199 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretutil
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// errTemplateOutputTooLarge is returned when a template renders more data than
+// fits into a Kubernetes secret.
+var errTemplateOutputTooLarge = errors.New("rendered data exceeds the maximum secret size")
+
+// maxTemplateSteps bounds the range iterations and template invocations of the
+// templates of a secret, so that a template cannot loop for an unbounded time
+// without rendering anything, e.g. {{ range 300000000 }}{{ end }}.
+const maxTemplateSteps = 100000
+
+// stepFuncName is the function called at every step of the execution of a
+// template to count the steps.
+const stepFuncName = "countTemplateStep"
+
+// errTemplateTooManySteps is returned when the templates of a secret execute
+// more than maxTemplateSteps steps.
+var errTemplateTooManySteps = errors.New("template execution exceeds the maximum number of steps")
+
+// templateFuncs are the only functions available to templates besides the
+// text/template builtins. None of them has access to the environment, the file
+// system or the network, and their errors never include the processed values.
+var templateFuncs = template.FuncMap{
+	"b64enc": func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	},
+	"b64dec": func(s string) (string, error) {
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return "", errors.New("b64dec: invalid base64 data")
+		}
+		return string(b), nil
+	},
+	"toJson": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", errors.New("toJson: value cannot be encoded")
+		}
+		return string(b), nil
+	},
+	"fromJson": func(s string) (interface{}, error) {
+		var v interface{}
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			return nil, errors.New("fromJson: invalid JSON data")
+		}
+		return v, nil
+	},
+	"pemCertificates": func(s string) (string, error) {
+		b, err := getCert([]byte(s))
+		if err != nil || len(b) == 0 {
+			return "", errors.New("pemCertificates: no certificate found")
+		}
+		return string(b), nil
+	},
+	"pemPrivateKey": func(s string) (string, error) {
+		b, err := getPrivateKey([]byte(s))
+		if err != nil {
+			return "", errors.New("pemPrivateKey: no supported private key found")
+		}
+		return string(b), nil
+	},
+}
+
+// limitedBuffer is a bytes.Buffer failing writes beyond its limit, so that a
+// template cannot render an unbounded amount of data.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errTemplateOutputTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// templateStep is the call to the step function inserted by limitSteps.
+var templateStep = func() parse.Node {
+	trees, err := parse.Parse("step", "{{"+stepFuncName+"}}", "", "", map[string]any{stepFuncName: func() {}})
+	if err != nil {
+		panic(err)
+	}
+	return trees["step"].Root.Nodes[0]
+}()
+
+// limitSteps inserts a call to the step function at the beginning of every
+// template and every range iteration of tmpl, which are the only constructs
+// repeating the execution of a template.
+func limitSteps(tmpl *template.Template) {
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch node := node.(type) {
+		case *parse.ListNode:
+			if node == nil {
+				return
+			}
+			for _, n := range node.Nodes {
+				walk(n)
+			}
+		case *parse.IfNode:
+			walk(node.List)
+			walk(node.ElseList)
+		case *parse.WithNode:
+			walk(node.List)
+			walk(node.ElseList)
+		case *parse.RangeNode:
+			walk(node.List)
+			walk(node.ElseList)
+			node.List.Nodes = append([]parse.Node{templateStep.Copy()}, node.List.Nodes...)
+		}
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil || t.Tree.Root == nil {
+			continue
+		}
+		walk(t.Tree.Root)
+		t.Tree.Root.Nodes = append([]parse.Node{templateStep.Copy()}, t.Tree.Root.Nodes...)
+	}
+}
+
+// RenderTemplateData executes the templates with the files returned from the
+// provider and returns a map that will be merged into the Kubernetes secret data
+// field. The templates are rendered in the order of their keys.
+func RenderTemplateData(templates map[string]string, files map[string][]byte) (map[string][]byte, error) {
+	templateData := make(map[string]string, len(files))
+	for path, content := range files {
+		templateData[path] = string(content)
+	}
+
+	keys := make([]string, 0, len(templates))
+	for key := range templates {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	// the steps are counted across all the templates
+	steps := 0
+	funcs := template.FuncMap{
+		stepFuncName: func() (string, error) {
+			steps++
+			if steps > maxTemplateSteps {
+				return "", errTemplateTooManySteps
+			}
+			return "", nil
+		},
+	}
+
+	datamap := make(map[string][]byte, len(templates))
+	for _, key := range keys {
+		dataKey := strings.TrimSpace(key)
+		if len(dataKey) == 0 {
+			return nil, fmt.Errorf("key in secretObject.template.data is empty")
+		}
+
+		tmpl, err := template.New(dataKey).Option("missingkey=error").Funcs(templateFuncs).Funcs(funcs).Parse(templates[key])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template for key %s: %w", dataKey, err)
+		}
+		limitSteps(tmpl)
+
+		out := &limitedBuffer{limit: corev1.MaxSecretSize}
+		if err := tmpl.Execute(out, templateData); err != nil {
+			return nil, fmt.Errorf("failed to render template for key %s: %w", dataKey, err)
+		}
+		datamap[dataKey] = out.Bytes()
+	}
+	return datamap, nil
+}
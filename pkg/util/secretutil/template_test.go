@@ -0,0 +1,196 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretutil
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderTemplateData(t *testing.T) {
+	files := map[string][]byte{
+		"host":        []byte("db.example.com"),
+		"user":        []byte("admin"),
+		"db/password": []byte("s3cr3t"),
+		"config":      []byte(`{"port":5432}`),
+		"cert":        []byte(certFile),
+	}
+
+	tests := []struct {
+		name                string
+		templates           map[string]string
+		expectedDataMap     map[string][]byte
+		expectedErrorString string
+	}{
+		{
+			name: "compose values from several files",
+			templates: map[string]string{
+				"url": `jdbc:postgresql://{{ .host }}:{{ (fromJson .config).port }}/app?user={{ .user }}&password={{ index . "db/password" }}`,
+			},
+			expectedDataMap: map[string][]byte{
+				"url": []byte("jdbc:postgresql://db.example.com:5432/app?user=admin&password=s3cr3t"),
+			},
+		},
+		{
+			name: "functions outside of the helpers are not available",
+			templates: map[string]string{
+				".dockerconfigjson": `{{ toJson (dict) }}`,
+			},
+			expectedErrorString: `failed to parse template for key .dockerconfigjson: template: .dockerconfigjson:1: function "dict" not defined`,
+		},
+		{
+			name: "base64 helpers",
+			templates: map[string]string{
+				"auth":    `{{ printf "%s:%s" .user (index . "db/password") | b64enc }}`,
+				"decoded": `{{ "YWRtaW4=" | b64dec }}`,
+			},
+			expectedDataMap: map[string][]byte{
+				"auth":    []byte("YWRtaW46czNjcjN0"),
+				"decoded": []byte("admin"),
+			},
+		},
+		{
+			name: "pem helpers",
+			templates: map[string]string{
+				"tls.crt": `{{ pemCertificates .cert }}`,
+				"tls.key": `{{ pemPrivateKey .cert }}`,
+			},
+			expectedDataMap: map[string][]byte{
+				"tls.crt": []byte(certPEM),
+				"tls.key": []byte(keyPEM),
+			},
+		},
+		{
+			name: "missing file",
+			templates: map[string]string{
+				"url": `{{ .missing }}`,
+			},
+			expectedErrorString: `failed to render template for key url: template: url:1:3: executing "url" at <.missing>: map has no entry for key "missing"`,
+		},
+		{
+			name: "helper errors do not leak values",
+			templates: map[string]string{
+				"port": `{{ (fromJson .user).port }}`,
+			},
+			expectedErrorString: `failed to render template for key port: template: port:1:4: executing "port" at <fromJson .user>: error calling fromJson: fromJson: invalid JSON data`,
+		},
+		{
+			name: "empty key",
+			templates: map[string]string{
+				" ": `{{ .host }}`,
+			},
+			expectedErrorString: "key in secretObject.template.data is empty",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			datamap, err := RenderTemplateData(test.templates, files)
+			if len(test.expectedErrorString) > 0 {
+				if err == nil || err.Error() != test.expectedErrorString {
+					t.Fatalf("expected err: %+v, got: %+v", test.expectedErrorString, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(datamap, test.expectedDataMap) {
+				t.Fatalf("expected data map %q, got %q", test.expectedDataMap, datamap)
+			}
+		})
+	}
+}
+
+func TestRenderTemplateDataOutputLimit(t *testing.T) {
+	files := map[string][]byte{
+		"big": []byte(strings.Repeat("a", 1024)),
+	}
+
+	_, err := RenderTemplateData(map[string]string{"big": `{{ range 2048 }}{{ $.big }}{{ end }}`}, files)
+	if err == nil || !strings.Contains(err.Error(), errTemplateOutputTooLarge.Error()) {
+		t.Fatalf("expected output limit error, got: %v", err)
+	}
+}
+
+func TestRenderTemplateDataStepLimit(t *testing.T) {
+	files := map[string][]byte{
+		"a": []byte("a"),
+		"b": []byte("b"),
+	}
+
+	tests := []struct {
+		name      string
+		templates map[string]string
+	}{
+		{
+			name:      "range over a large integer",
+			templates: map[string]string{"loop": `{{ range 300000000 }}{{ end }}`},
+		},
+		{
+			name:      "nested ranges",
+			templates: map[string]string{"loop": `{{ range 1000 }}{{ range 1000 }}{{ end }}{{ end }}`},
+		},
+		{
+			name:      "templates invoking each other",
+			templates: map[string]string{"loop": doublingTemplates(30)},
+		},
+		{
+			name: "steps are counted across keys",
+			templates: map[string]string{
+				"loop1": `{{ range 60000 }}{{ end }}`,
+				"loop2": `{{ range 60000 }}{{ end }}`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start := time.Now()
+			_, err := RenderTemplateData(test.templates, files)
+			if !errors.Is(err, errTemplateTooManySteps) {
+				t.Fatalf("expected step limit error, got: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Fatalf("expected the execution to stop early, took %s", elapsed)
+			}
+		})
+	}
+
+	// ranges within the limit are rendered
+	datamap, err := RenderTemplateData(map[string]string{"keys": `{{ range $k, $v := . }}{{ $k }}{{ end }}{{ range 3 }}.{{ end }}`}, files)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := string(datamap["keys"]); got != "ab..." {
+		t.Fatalf("expected %q, got %q", "ab...", got)
+	}
+}
+
+// doublingTemplates returns a template invoking the next one twice, n times.
+func doublingTemplates(n int) string {
+	var b strings.Builder
+	for i := range n {
+		fmt.Fprintf(&b, `{{ define "t%d" }}{{ template "t%d" }}{{ template "t%d" }}{{ end }}`, i, i+1, i+1)
+	}
+	fmt.Fprintf(&b, `{{ define "t%d" }}{{ end }}{{ template "t0" }}`, n)
+	return b.String()
+}
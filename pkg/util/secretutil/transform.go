@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretutil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+
+	secretsyncv1alpha1 "sigs.k8s.io/secrets-store-sync-controller/api/v1alpha1"
+)
+
+// applyTransforms applies the transforms in order to the content of a key.
+// The errors never include the content.
+func applyTransforms(content []byte, key string, transforms []secretsyncv1alpha1.SecretDataTransform) ([]byte, error) {
+	for _, transform := range transforms {
+		var err error
+		switch transform {
+		case secretsyncv1alpha1.SecretDataTransformBase64Decode:
+			content, err = base64Decode(content)
+		case secretsyncv1alpha1.SecretDataTransformBase64Encode:
+			content = []byte(base64.StdEncoding.EncodeToString(content))
+		case secretsyncv1alpha1.SecretDataTransformTrimSpace:
+			content = bytes.TrimSpace(content)
+		case secretsyncv1alpha1.SecretDataTransformGunzip:
+			content, err = gunzip(content)
+		case secretsyncv1alpha1.SecretDataTransformHexDecode:
+			content, err = hexDecode(content)
+		case secretsyncv1alpha1.SecretDataTransformNormalizeLineEndings:
+			content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+			content = bytes.ReplaceAll(content, []byte("\r"), []byte("\n"))
+		default:
+			return nil, fmt.Errorf("transform %s for key %s is not supported", transform, key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply transform %s to key %s: %w", transform, key, err)
+		}
+	}
+	return content, nil
+}
+
+func base64Decode(content []byte) ([]byte, error) {
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(len(content)))
+	n, err := base64.StdEncoding.Decode(decoded, content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 data")
+	}
+	return decoded[:n], nil
+}
+
+func hexDecode(content []byte) ([]byte, error) {
+	decoded := make([]byte, hex.DecodedLen(len(content)))
+	n, err := hex.Decode(decoded, content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex data")
+	}
+	return decoded[:n], nil
+}
+
+// gunzip decompresses the content, refusing to produce more data than fits
+// into a Kubernetes secret.
+func gunzip(content []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip data")
+	}
+	defer zr.Close()
+
+	decompressed, err := io.ReadAll(io.LimitReader(zr, corev1.MaxSecretSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip data")
+	}
+	if len(decompressed) > corev1.MaxSecretSize {
+		return nil, fmt.Errorf("decompressed data exceeds the maximum secret size")
+	}
+	return decompressed, nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretutil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	secretsyncv1alpha1 "sigs.k8s.io/secrets-store-sync-controller/api/v1alpha1"
+)
+
+func gzipData(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestApplyTransforms(t *testing.T) {
+	tests := []struct {
+		name                string
+		content             []byte
+		transforms          []secretsyncv1alpha1.SecretDataTransform
+		expected            []byte
+		expectedErrorString string
+	}{
+		{
+			name:     "no transforms",
+			content:  []byte("value\n"),
+			expected: []byte("value\n"),
+		},
+		{
+			name:       "base64 decode and trim",
+			content:    []byte("dmFsdWUK\n"),
+			transforms: []secretsyncv1alpha1.SecretDataTransform{"trimSpace", "base64Decode", "trimSpace"},
+			expected:   []byte("value"),
+		},
+		{
+			name:       "base64 encode",
+			content:    []byte("value"),
+			transforms: []secretsyncv1alpha1.SecretDataTransform{"base64Encode"},
+			expected:   []byte("dmFsdWU="),
+		},
+		{
+			name:       "gunzip base64 encoded content",
+			content:    []byte(base64.StdEncoding.EncodeToString(gzipData(t, []byte("value")))),
+			transforms: []secretsyncv1alpha1.SecretDataTransform{"base64Decode", "gunzip"},
+			expected:   []byte("value"),
+		},
+		{
+			name:       "hex decode",
+			content:    []byte("76616c7565"),
+			transforms: []secretsyncv1alpha1.SecretDataTransform{"hexDecode"},
+			expected:   []byte("value"),
+		},
+		{
+			name:       "normalize line endings",
+			content:    []byte("a\r\nb\rc\n"),
+			transforms: []secretsyncv1alpha1.SecretDataTransform{"normalizeLineEndings"},
+			expected:   []byte("a\nb\nc\n"),
+		},
+		{
+			name:                "invalid base64 data",
+			content:             []byte("s3cr3t!"),
+			transforms:          []secretsyncv1alpha1.SecretDataTransform{"base64Decode"},
+			expectedErrorString: "failed to apply transform base64Decode to key key: invalid base64 data",
+		},
+		{
+			name:                "invalid gzip data",
+			content:             []byte("s3cr3t"),
+			transforms:          []secretsyncv1alpha1.SecretDataTransform{"gunzip"},
+			expectedErrorString: "failed to apply transform gunzip to key key: invalid gzip data",
+		},
+		{
+			name:                "decompressed data too large",
+			content:             gzipData(t, make([]byte, corev1.MaxSecretSize+1)),
+			transforms:          []secretsyncv1alpha1.SecretDataTransform{"gunzip"},
+			expectedErrorString: "failed to apply transform gunzip to key key: decompressed data exceeds the maximum secret size",
+		},
+		{
+			name:                "unsupported transform",
+			content:             []byte("value"),
+			transforms:          []secretsyncv1alpha1.SecretDataTransform{"rot13"},
+			expectedErrorString: "transform rot13 for key key is not supported",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := applyTransforms(test.content, "key", test.transforms)
+			if len(test.expectedErrorString) > 0 {
+				assert.EqualError(t, err, test.expectedErrorString)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestBuildKubeSecretDataTransformsBeforeTLSSplit(t *testing.T) {
+	secretObjData := []secretsyncv1alpha1.SecretObjectData{
+		{
+			SourcePath: "cert",
+			TargetKey:  corev1.TLSCertKey,
+			Transforms: []secretsyncv1alpha1.SecretDataTransform{"base64Decode"},
+		},
+	}
+	files := map[string][]byte{
+		"cert": []byte(base64.StdEncoding.EncodeToString([]byte(certFile))),
+	}
+
+	datamap, _, err := BuildKubeSecretData(secretObjData, corev1.SecretTypeTLS, files, secretsyncv1alpha1.MissingKeyPolicyFail)
+	assert.NoError(t, err)
+	assert.Equal(t, certPEM, string(datamap[corev1.TLSCertKey]))
+}
@@ -143,6 +143,76 @@ SLEEP_TIME=1
     "Label keys must not exceed 317 characters (254 for prefix+separator, 63 for name), label values must not exceed 63 characters."
 }
 
+@test "SecretSync syncs to a secret with a custom name" {
+  create_namespace custom-name-namespace
+
+  deploy_and_wait_for_resource custom-name-namespace "$BATS_RESOURCE_MANIFESTS_DIR/e2e-providerspc.yaml" e2e-providerspc secretproviderclasses.secrets-store.csi.x-k8s.io
+  deploy_and_wait_for_resource custom-name-namespace "$BATS_RESOURCE_YAML_DIR/custom_name_secretsync.yaml" sse2esecret secretsyncs.secret-sync.x-k8s.io
+
+  # The secret named after secretObject.name is owned by the SecretSync
+  cmd="compare_owner_count sse2ecustomsecret custom-name-namespace 1"
+  wait_for_process $WAIT_TIME $SLEEP_TIME "$cmd"
+  [ "$(kubectl get secret sse2ecustomsecret -n custom-name-namespace -o jsonpath='{.metadata.ownerReferences[0].name}')" = "sse2esecret" ]
+  [ "$(kubectl get secret sse2ecustomsecret -n custom-name-namespace -o jsonpath='{.data.bar}' | base64 --decode)" = "secret" ]
+
+  # Renaming the secret syncs the new one and deletes the previous one
+  kubectl patch secretsync sse2esecret -n custom-name-namespace --type merge -p '{"spec":{"secretObject":{"name":"sse2erenamedsecret"}}}'
+  cmd="compare_owner_count sse2erenamedsecret custom-name-namespace 1"
+  wait_for_process $WAIT_TIME $SLEEP_TIME "$cmd"
+  cmd="kubectl get secret sse2ecustomsecret -n custom-name-namespace"
+  wait_for_process $WAIT_TIME $SLEEP_TIME "! $cmd"
+
+  kubectl delete secretsync sse2esecret -n custom-name-namespace --timeout=${WAIT_TIME}s
+  cmd="kubectl get secret sse2erenamedsecret -n custom-name-namespace"
+  wait_for_process $WAIT_TIME $SLEEP_TIME "! $cmd"
+}
+
+@test "Orphan deletion policy keeps the secret and a new SecretSync takes it over" {
+  create_namespace orphan-namespace
+
+  deploy_and_wait_for_resource orphan-namespace "$BATS_RESOURCE_MANIFESTS_DIR/e2e-providerspc.yaml" e2e-providerspc secretproviderclasses.secrets-store.csi.x-k8s.io
+  deploy_and_wait_for_resource orphan-namespace "$BATS_RESOURCE_YAML_DIR/orphan_secretsync.yaml" sse2esecret secretsyncs.secret-sync.x-k8s.io
+
+  cmd="compare_owner_count sse2esecret orphan-namespace 1"
+  wait_for_process $WAIT_TIME $SLEEP_TIME "$cmd"
+
+  # The finalizer releases the secret, the SecretSync is gone
+  kubectl delete secretsync sse2esecret -n orphan-namespace --timeout=${WAIT_TIME}s
+
+  # The secret is kept without owner, with the controller label
+  cmd="compare_owner_count sse2esecret orphan-namespace 0"
+  wait_for_process $WAIT_TIME $SLEEP_TIME "$cmd"
+  kubectl get secret sse2esecret -n orphan-namespace -o json | jq -e '.metadata.labels | has("secrets-store.sync.x-k8s.io")'
+
+  # A new SecretSync takes the orphaned secret over
+  kubectl apply -n orphan-namespace -f $BATS_RESOURCE_MANIFESTS_DIR/e2e-secret-sync.yaml
+  cmd="compare_owner_count sse2esecret orphan-namespace 1"
+  wait_for_process $WAIT_TIME $SLEEP_TIME "$cmd"
+
+  kubectl delete secretsync sse2esecret -n orphan-namespace --timeout=${WAIT_TIME}s
+  cmd="kubectl get secret sse2esecret -n orphan-namespace"
+  wait_for_process $WAIT_TIME $SLEEP_TIME "! $cmd"
+}
+
+@test "Retain deletion policy keeps the secret and releases it from the controller" {
+  create_namespace retain-namespace
+
+  deploy_and_wait_for_resource retain-namespace "$BATS_RESOURCE_MANIFESTS_DIR/e2e-providerspc.yaml" e2e-providerspc secretproviderclasses.secrets-store.csi.x-k8s.io
+  deploy_and_wait_for_resource retain-namespace "$BATS_RESOURCE_YAML_DIR/retain_secretsync.yaml" sse2esecret secretsyncs.secret-sync.x-k8s.io
+
+  cmd="compare_owner_count sse2esecret retain-namespace 1"
+  wait_for_process $WAIT_TIME $SLEEP_TIME "$cmd"
+
+  # The finalizer releases the secret, the SecretSync is gone
+  kubectl delete secretsync sse2esecret -n retain-namespace --timeout=${WAIT_TIME}s
+
+  # The secret and its data are kept, without owner and controller label
+  cmd="compare_owner_count sse2esecret retain-namespace 0"
+  wait_for_process $WAIT_TIME $SLEEP_TIME "$cmd"
+  [ "$(kubectl get secret sse2esecret -n retain-namespace -o jsonpath='{.data.bar}' | base64 --decode)" = "secret" ]
+  kubectl get secret sse2esecret -n retain-namespace -o json | jq -e '.metadata.labels // {} | has("secrets-store.sync.x-k8s.io") | not'
+}
+
 teardown_file() {
   archive_provider "app=secrets-store-sync-controller" || true
   archive_info || true
@@ -150,6 +220,9 @@ teardown_file() {
   run kubectl delete namespace test-v1alpha1
   run kubectl delete namespace spc-namespace
   run kubectl delete namespace ss-namespace
+  run kubectl delete namespace custom-name-namespace
+  run kubectl delete namespace orphan-namespace
+  run kubectl delete namespace retain-namespace
 
   echo "Done cleaning up e2e tests"
 }